@@ -1,16 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"bode.fun/2fa/core"
+	"bode.fun/otp"
+	"bode.fun/otp/hotp"
 	"bode.fun/otp/totp"
 	"github.com/spf13/cobra"
 )
 
 func NewAddCommand(app core.App) *cobra.Command {
 	command := &cobra.Command{
-		Use:     "add issuer account base32-secret",
+		Use:     "add issuer account secret",
 		Aliases: []string{"a"},
 		Short:   "Add a new OTP token to your collection",
 		Args:    cobra.MatchAll(cobra.ExactArgs(3)),
@@ -25,6 +28,21 @@ func NewAddCommand(app core.App) *cobra.Command {
 				return err
 			}
 
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return err
+			}
+
+			encodingName, err := cmd.Flags().GetString("encoding")
+			if err != nil {
+				return err
+			}
+
+			encoding, err := totp.ParseEncoding(encodingName)
+			if err != nil {
+				return err
+			}
+
 			if !(digits >= 6 && digits <= 8) {
 				return fmt.Errorf("digits has to be between 6 and 8 digits")
 			}
@@ -40,15 +58,30 @@ func NewAddCommand(app core.App) *cobra.Command {
 				totp.WithPeriod(period),
 			}
 
-			totpInstance, err := totp.NewFromBase32(secret, otpions...)
+			totpInstance, err := totp.NewFromEncoded(secret, encoding, append(otpions, totp.WithLockedMemory())...)
+			if errors.Is(err, totp.ErrLockedMemory) {
+				app.Logger().Warn("can't lock the secret into memory, it might be written to swap", "err", err)
+				totpInstance, err = totp.NewFromEncoded(secret, encoding, otpions...)
+			}
 			if err != nil {
 				return err
 			}
+			defer totpInstance.Destroy()
 
 			otpUrl := totpInstance.ToUrl()
 
 			identifier := totpInstance.Label()
 
+			isConflict, err := checkConflicts(app, identifier, totpInstance)
+			if err != nil {
+				return err
+			}
+
+			if isConflict && !force {
+				app.Logger().Warn("the token was not added, use --force to add it anyway")
+				return nil
+			}
+
 			err = app.DB().Set([]byte(identifier), []byte(otpUrl))
 			if err != nil {
 				return err
@@ -66,7 +99,7 @@ func NewAddCommand(app core.App) *cobra.Command {
 			app.Logger().Info(
 				"please check the code to see, if it worked",
 				"code",
-				code,
+				code.String(),
 			)
 
 			err = app.DB().Sync()
@@ -85,5 +118,106 @@ You can pick between 6, 7 or 8 digits.`)
 
 	command.Flags().Uint("period", 30, `The time in seconds, after which the token changes`)
 
+	command.Flags().BoolP("force", "f", false, `Add the token, even if its secret is already stored
+under a different id or the id is used by a different token.`)
+
+	command.Flags().StringP("encoding", "e", string(totp.EncodingBase32), `The encoding of the secret.
+You can pick between base32, hex, base64 or auto to detect it.
+Spaces and dashes in the secret are ignored.`)
+
 	return command
 }
+
+// A token, whose secret can be compared with the stored tokens.
+type secretToken interface {
+	otp.Metadata
+	Secret() []byte
+}
+
+// Warn about stored tokens, that share the secret of the new token under
+// a different id, or that use the id for a different token.
+// Tokens, that can't be read, are skipped.
+func checkConflicts(app core.App, identifier string, token secretToken) (bool, error) {
+	// The fingerprints are only compared and never stored,
+	// so a random key for this run is enough
+	fingerprintKey, err := hotp.GenerateSecret(32)
+	if err != nil {
+		return false, err
+	}
+	defer hotp.Zero(fingerprintKey)
+
+	fingerprint, err := token.Fingerprint(fingerprintKey)
+	if err != nil {
+		return false, err
+	}
+
+	secretFingerprint, err := newSecretFingerprint(fingerprintKey, token)
+	if err != nil {
+		return false, err
+	}
+
+	err = app.DB().Reset()
+	if err != nil {
+		return false, err
+	}
+
+	err = migrateIdentifiers(app)
+	if err != nil {
+		return false, err
+	}
+
+	storedIdentifiers, err := app.DB().Keys()
+	if err != nil {
+		return false, err
+	}
+
+	isConflict := false
+
+	for _, storedIdentifier := range storedIdentifiers {
+		key, err := getKey(app, string(storedIdentifier))
+		if err != nil {
+			continue
+		}
+
+		var storedSecretFingerprint hotp.Fingerprint
+		storedFingerprint, err := key.Fingerprint(fingerprintKey)
+		if err == nil {
+			storedSecretFingerprint, err = newSecretFingerprint(fingerprintKey, key)
+		}
+		key.Destroy()
+		if err != nil {
+			return false, err
+		}
+
+		isSameSecret := storedSecretFingerprint.Equal(secretFingerprint)
+		isSameToken := storedFingerprint.Equal(fingerprint)
+		isSameIdentifier := string(storedIdentifier) == identifier
+
+		if isSameSecret && !isSameIdentifier {
+			app.Logger().Warn(
+				"the secret is already stored under a different id",
+				"id", string(storedIdentifier),
+			)
+			isConflict = true
+		}
+
+		if !isSameToken && isSameIdentifier {
+			app.Logger().Warn(
+				"a different token is already stored under this id",
+				"id", identifier,
+			)
+			isConflict = true
+		}
+	}
+
+	return isConflict, nil
+}
+
+// The fingerprint of the secret alone, so tokens with the same secret
+// match, even if their algorithm, digits or period differ.
+func newSecretFingerprint(key []byte, token secretToken) (hotp.Fingerprint, error) {
+	secret := token.Secret()
+	defer hotp.Zero(secret)
+
+	return hotp.NewFingerprint(key, secret, "", 0, 0)
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"bode.fun/2fa/core"
-	"bode.fun/otp/totp"
+	"bode.fun/otp"
+	"bode.fun/otp/hotp"
 	"github.com/spf13/cobra"
 )
 
@@ -23,13 +26,17 @@ func NewGetCommand(app core.App) *cobra.Command {
 				return err
 			}
 
-			// TODO: Add prefixed zeros
+			err = migrateIdentifiers(app)
+			if err != nil {
+				return err
+			}
+
 			// TODO: Add a custom error message when key is not found
 			code, err := getOtpCode(app, identifier)
 			if err != nil {
 				return err
 			}
-			fmt.Println(code)
+			fmt.Println(code.String())
 
 			return nil
 		},
@@ -38,18 +45,47 @@ func NewGetCommand(app core.App) *cobra.Command {
 	return command
 }
 
-func getOtpCode(app core.App, identifier string) (uint32, error) {
-	var otpCode uint32
-	otpUrlAsBytes, err := app.DB().Get([]byte(identifier))
+// Calculate the current code of the token.
+// The counter of hotp tokens is advanced and stored, so every code
+// is only handed out once.
+func getOtpCode(app core.App, identifier string) (hotp.Code, error) {
+	var otpCode hotp.Code
+	key, err := getKey(app, identifier)
 	if err != nil {
 		return otpCode, err
 	}
+	defer key.Destroy()
+
+	otpCode = key.NextCode(time.Now())
 
-	totpInstance, err := totp.NewFromUrl(string(otpUrlAsBytes))
+	if key.Type() == otp.TypeHotp {
+		err = app.DB().Set([]byte(identifier), []byte(key.ToUrl()))
+		if err != nil {
+			return otpCode, err
+		}
+
+		err = app.DB().Sync()
+		if err != nil {
+			return otpCode, err
+		}
+	}
+
+	return otpCode, nil
+}
+
+// Load a token of any type from the database.
+// The caller has to destroy it after use.
+func getKey(app core.App, identifier string) (*otp.Key, error) {
+	otpUrlAsBytes, err := app.DB().Get([]byte(identifier))
 	if err != nil {
-		return otpCode, err
+		return nil, err
+	}
+
+	key, err := otp.Parse(string(otpUrlAsBytes), otp.WithLockedMemory())
+	if errors.Is(err, hotp.ErrLockedMemory) {
+		app.Logger().Debug("can't lock the secret into memory", "id", identifier, "err", err)
+		return otp.Parse(string(otpUrlAsBytes))
 	}
 
-	otpCode = totpInstance.Now()
-	return otpCode, err
+	return key, err
 }
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"time"
+
 	"bode.fun/2fa/core"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +18,11 @@ func NewListCommand(app core.App) *cobra.Command {
 				return err
 			}
 
+			err = migrateIdentifiers(app)
+			if err != nil {
+				return err
+			}
+
 			identifiers, err := app.DB().Keys()
 			if err != nil {
 				return err
@@ -29,13 +36,38 @@ func NewListCommand(app core.App) *cobra.Command {
 			}
 
 			for _, identifier := range identifiers {
-				var code uint32
-				code, _ = getOtpCode(app, string(identifier))
-				app.Logger().Print(
-					nil,
+				key, err := getKey(app, string(identifier))
+				if err != nil {
+					app.Logger().Warn(
+						"can't read the OTP token",
+						"id", string(identifier),
+						"err", err,
+					)
+					continue
+				}
+
+				now := time.Now()
+				keyvals := []interface{}{
 					"id", string(identifier),
-					"code", code,
-				)
+				}
+
+				totpInstance, isTotp := key.Totp()
+				if isTotp {
+					keyvals = append(keyvals,
+						"code", key.Code(now).String(),
+						"remaining", totpInstance.Remaining(now).Truncate(time.Second),
+					)
+				} else {
+					// Showing a hotp code would not consume it, so it
+					// is only handed out by get
+					keyvals = append(keyvals,
+						"counter", key.Counter(),
+					)
+				}
+
+				app.Logger().Print(nil, keyvals...)
+
+				key.Destroy()
 			}
 
 			return nil
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"net/url"
+
+	"bode.fun/2fa/core"
+	"bode.fun/otp"
+)
+
+// Move tokens, that are stored under the id of older versions in the
+// form account:issuer, to the id issuer:account of Label.
+// Tokens with a custom id, or whose new id is already taken, are kept.
+//
+// The database has to be reset by the caller.
+func migrateIdentifiers(app core.App) error {
+	identifiers, err := app.DB().Keys()
+	if err != nil {
+		return err
+	}
+
+	isStored := map[string]bool{}
+	for _, identifier := range identifiers {
+		isStored[string(identifier)] = true
+	}
+
+	hasMigrated := false
+
+	for _, identifier := range identifiers {
+		key, err := getKey(app, string(identifier))
+		if err != nil {
+			continue
+		}
+
+		newIdentifier := key.Label()
+		isLegacy := string(identifier) == legacyLabel(key)
+		key.Destroy()
+
+		if !isLegacy || string(identifier) == newIdentifier {
+			continue
+		}
+
+		if isStored[newIdentifier] {
+			app.Logger().Warn(
+				"the token is stored under its old and its new id",
+				"id", string(identifier),
+				"new id", newIdentifier,
+			)
+			continue
+		}
+
+		otpUrl, err := app.DB().Get(identifier)
+		if err != nil {
+			return err
+		}
+
+		err = app.DB().Set([]byte(newIdentifier), otpUrl)
+		if err != nil {
+			return err
+		}
+
+		err = app.DB().Delete(identifier)
+		if err != nil {
+			return err
+		}
+
+		isStored[newIdentifier] = true
+		hasMigrated = true
+
+		app.Logger().Info(
+			"moved the OTP token to its new id",
+			"id", string(identifier),
+			"new id", newIdentifier,
+		)
+	}
+
+	if !hasMigrated {
+		return nil
+	}
+
+	return app.DB().Sync()
+}
+
+// The id of older versions in the form account:issuer
+func legacyLabel(key *otp.Key) string {
+	label := key.Account()
+
+	if key.Issuer() != "" {
+		label = label + ":" + key.Issuer()
+	}
+
+	return url.PathEscape(label)
+}
@@ -0,0 +1,232 @@
+// Package compat checks otpauth Key URIs against the parameters, that
+// popular authenticator apps support.
+//
+// Run the checks before a QR code is handed out, so users don't scan
+// keys, that their app silently calculates wrong codes for.
+//
+// Example:
+//
+//	warnings, err := compat.CheckUrl(totp.ToUrl(), compat.Profiles...)
+//	if err != nil {
+//		return err
+//	}
+//
+//	for _, warning := range warnings {
+//		fmt.Println(warning)
+//	}
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/uri"
+)
+
+// The defaults of the spec for parameters, that are not set.
+const (
+	defaultAlgorithm = "SHA1"
+	defaultDigits    = 6
+	defaultPeriod    = 30
+)
+
+// Profile describes the parameters, that an authenticator app supports.
+// Empty lists support every value.
+type Profile struct {
+	Name       string
+	Types      []string
+	Algorithms []string
+	Digits     []uint
+	Periods    []uint
+
+	// Whether the app keeps parameters, that are not part of the spec,
+	// like t0, offset and alphabet.
+	SupportsExtensions bool
+}
+
+var (
+	// Some versions of Google Authenticator ignore the algorithm, digits
+	// and period and always use the defaults of the spec.
+	GoogleAuthenticator = Profile{
+		Name:       "Google Authenticator",
+		Types:      []string{uri.TypeTotp, uri.TypeHotp},
+		Algorithms: []string{"SHA1"},
+		Digits:     []uint{6},
+		Periods:    []uint{30},
+	}
+
+	MicrosoftAuthenticator = Profile{
+		Name:       "Microsoft Authenticator",
+		Types:      []string{uri.TypeTotp},
+		Algorithms: []string{"SHA1"},
+		Digits:     []uint{6},
+		Periods:    []uint{30},
+	}
+
+	Aegis = Profile{
+		Name:       "Aegis",
+		Types:      []string{uri.TypeTotp, uri.TypeHotp},
+		Algorithms: []string{"SHA1", "SHA256", "SHA512"},
+	}
+
+	FreeOtp = Profile{
+		Name:       "FreeOTP",
+		Types:      []string{uri.TypeTotp, uri.TypeHotp},
+		Algorithms: []string{"SHA1", "SHA256", "SHA512"},
+	}
+
+	TwoFas = Profile{
+		Name:       "2FAS",
+		Types:      []string{uri.TypeTotp, uri.TypeHotp},
+		Algorithms: []string{"SHA1", "SHA256", "SHA512"},
+	}
+)
+
+// All known profiles.
+var Profiles = []Profile{
+	GoogleAuthenticator,
+	MicrosoftAuthenticator,
+	Aegis,
+	FreeOtp,
+	TwoFas,
+}
+
+// Find a known profile by its name case-insensitively.
+func ProfileByName(name string) (Profile, bool) {
+	for _, profile := range Profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+
+	return Profile{}, false
+}
+
+// Warning about a parameter, that an app does not support.
+type Warning struct {
+	Profile   string
+	Parameter string
+	Message   string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Profile, w.Parameter, w.Message)
+}
+
+// Check the key against the profile and return every warning.
+// Parameters, that are not set, are checked with the defaults of the spec.
+func (p Profile) Check(key *uri.Key) []Warning {
+	warnings := []Warning{}
+
+	warn := func(parameter string, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{
+			Profile:   p.Name,
+			Parameter: parameter,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	if !containsString(p.Types, key.Type) {
+		warn("type", "%s keys are not supported", key.Type)
+
+		// The other parameters don't matter, if the key can't be added
+		return warnings
+	}
+
+	algorithm := strings.ToUpper(key.Algorithm)
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+
+	if !containsString(p.Algorithms, algorithm) {
+		warn("algorithm", "%s is not supported", algorithm)
+	}
+
+	digits := key.Digits
+	if digits == 0 {
+		digits = defaultDigits
+	}
+
+	if !containsUint(p.Digits, digits) {
+		warn("digits", "%d digits are not supported", digits)
+	}
+
+	period := key.Period
+	if period == 0 {
+		period = defaultPeriod
+	}
+
+	if key.Type == uri.TypeTotp && !containsUint(p.Periods, period) {
+		warn("period", "a period of %d seconds is not supported", period)
+	}
+
+	if !p.SupportsExtensions {
+		if key.T0 != 0 {
+			warn("t0", "a custom epoch is not supported")
+		}
+
+		if key.Offset != nil {
+			warn("offset", "a fixed truncation offset is not supported")
+		}
+
+		if key.Alphabet != "" {
+			warn("alphabet", "codes with other characters than decimal digits are not supported")
+		}
+	}
+
+	return warnings
+}
+
+// Check the key against every profile and return every warning.
+func Check(key *uri.Key, profiles ...Profile) []Warning {
+	warnings := []Warning{}
+
+	for _, profile := range profiles {
+		warnings = append(warnings, profile.Check(key)...)
+	}
+
+	return warnings
+}
+
+// Check a Key URI, like the one of ToUrl, against every profile.
+// An error is returned, if the URI can't be parsed.
+func CheckUrl(rawUri string, profiles ...Profile) ([]Warning, error) {
+	key, err := uri.ParseLenient(rawUri)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the parameters are checked, so the secret is not needed
+	hotp.Zero(key.Secret)
+
+	return Check(key, profiles...), nil
+}
+
+func containsString(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUint(values []uint, value uint) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
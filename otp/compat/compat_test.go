@@ -0,0 +1,72 @@
+package compat_test
+
+import (
+	"testing"
+
+	"bode.fun/otp/compat"
+	"bode.fun/otp/totp"
+	"bode.fun/otp/uri"
+	"github.com/matryer/is"
+)
+
+func Test_Check(t *testing.T) {
+	is := is.New(t)
+
+	{
+		// The defaults of the spec are supported by every app
+		warnings, err := compat.CheckUrl("otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example", compat.Profiles...)
+		is.NoErr(err)
+		is.Equal(0, len(warnings))
+	}
+
+	{
+		totpInstance, err := totp.New(
+			[]byte("12345678901234567890123456789012"),
+			totp.WithAlgorithm(totp.Sha256),
+			totp.WithDigits(8),
+		)
+		is.NoErr(err)
+
+		warnings, err := compat.CheckUrl(totpInstance.ToUrl(), compat.MicrosoftAuthenticator, compat.Aegis)
+		is.NoErr(err)
+		is.Equal(2, len(warnings))
+		is.Equal("Microsoft Authenticator: algorithm: SHA256 is not supported", warnings[0].String())
+		is.Equal("digits", warnings[1].Parameter)
+	}
+
+	{
+		key := &uri.Key{Type: uri.TypeHotp, Counter: 1}
+
+		warnings := compat.MicrosoftAuthenticator.Check(key)
+		is.Equal(1, len(warnings))
+		is.Equal("type", warnings[0].Parameter)
+
+		is.Equal(0, len(compat.GoogleAuthenticator.Check(key)))
+	}
+
+	{
+		offset := uint8(4)
+		key := &uri.Key{Type: uri.TypeTotp, Period: 60, T0: 100, Offset: &offset}
+
+		warnings := compat.FreeOtp.Check(key)
+		is.Equal(2, len(warnings))
+
+		warnings = compat.GoogleAuthenticator.Check(key)
+		is.Equal(3, len(warnings))
+		is.Equal("period", warnings[0].Parameter)
+	}
+
+	{
+		profile, ok := compat.ProfileByName("google authenticator")
+		is.True(ok)
+		is.Equal(compat.GoogleAuthenticator.Name, profile.Name)
+
+		_, ok = compat.ProfileByName("unknown")
+		is.True(!ok)
+	}
+
+	{
+		_, err := compat.CheckUrl("https://example.com", compat.Profiles...)
+		is.True(err != nil)
+	}
+}
@@ -0,0 +1,122 @@
+package hotp
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[Algorithm]func() hash.Hash{
+		Sha1:   sha1.New,
+		Sha256: sha256.New,
+		Sha512: sha512.New,
+	}
+)
+
+// Register an additional hash function for HMAC under the name.
+// Afterwards the algorithm can be used everywhere, in calculation,
+// validation and in otpauth urls. The name is case-insensitive.
+//
+// Registering a name a second time replaces the hash function,
+// but the built-in algorithms sha1, sha256 and sha512 can not be replaced.
+// The dynamic truncation reads up to the 20th byte of the digest,
+// so hash functions with a smaller output size are rejected.
+//
+// Example:
+//
+//	err := RegisterAlgorithm("sha3-256", sha3.New256)
+func RegisterAlgorithm(name string, hashFunction func() hash.Hash) error {
+	algorithm := normalizeAlgorithm(name)
+
+	if algorithm == "" || hashFunction == nil {
+		return fmt.Errorf("%w: %q needs a name and a hash function", ErrInvalidAlgorithm, name)
+	}
+
+	if isBuiltIn(algorithm) {
+		return fmt.Errorf("%w: the built-in algorithm %q can not be replaced", ErrInvalidAlgorithm, name)
+	}
+
+	size := hashFunction().Size()
+	if size < minDigestSize {
+		return fmt.Errorf("%w: %q has a digest of %d bytes, which is less than %d bytes", ErrInvalidAlgorithm, name, size, minDigestSize)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry[algorithm] = hashFunction
+	return nil
+}
+
+// Remove a registered algorithm.
+// The built-in algorithms sha1, sha256 and sha512 can not be removed.
+func UnregisterAlgorithm(name string) error {
+	algorithm := normalizeAlgorithm(name)
+
+	if isBuiltIn(algorithm) {
+		return fmt.Errorf("%w: the built-in algorithm %q can not be removed", ErrInvalidAlgorithm, name)
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	delete(registry, algorithm)
+	return nil
+}
+
+// The dynamic truncation of RFC 4226 section 5.3 reads 4 bytes at an
+// offset of up to 15, which needs the 20 byte digest of sha1.
+const minDigestSize = sha1.Size
+
+func isBuiltIn(algorithm Algorithm) bool {
+	return algorithm == Sha1 || algorithm == Sha256 || algorithm == Sha512
+}
+
+// Parse the name of an algorithm case-insensitively.
+// Authenticator apps usually use the uppercase form, like "SHA1".
+func ParseAlgorithm(name string) (Algorithm, error) {
+	algorithm := normalizeAlgorithm(name)
+
+	_, err := algorithm.ToHashFunction()
+	if err != nil {
+		return "", err
+	}
+
+	return algorithm, nil
+}
+
+// The hash function of a built-in or registered algorithm.
+func (a Algorithm) ToHashFunction() (func() hash.Hash, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	hashFunction, isRegistered := registry[a]
+	if !isRegistered {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAlgorithm, string(a))
+	}
+
+	return hashFunction, nil
+}
+
+// The recommended secret size in bytes for the algorithm.
+// It matches the output size of the hash function as recommended
+// by RFC 4226 section 4 and used by the test vectors of RFC 6238.
+// Unknown algorithms have a size of 0.
+func (a Algorithm) SecretSize() uint {
+	hashFunction, err := a.ToHashFunction()
+	if err != nil {
+		return 0
+	}
+
+	return uint(hashFunction().Size())
+}
+
+func normalizeAlgorithm(name string) Algorithm {
+	return Algorithm(strings.ToLower(strings.TrimSpace(name)))
+}
@@ -0,0 +1,179 @@
+package hotp
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidCode = errors.New("invalid otp code")
+
+// Code is a One Time Password together with its amount of digits
+// and the alphabet of its characters.
+//
+// Codes with a leading zero, like 07081804, keep their zero when
+// they are formatted.
+type Code struct {
+	value  uint32
+	digits uint
+
+	// The alphabet is empty for decimal codes
+	alphabet string
+}
+
+// Create a decimal Code from its numeric value and its amount of digits.
+func NewCode(value uint32, digits uint) Code {
+	return Code{
+		value:  value,
+		digits: digits,
+	}
+}
+
+// Parse a code entered by a user.
+// Spaces and dashes, used to group the digits, are ignored.
+// The code has to consist of exactly the given amount of digits.
+//
+// Example:
+//
+//	code, err := ParseCode("070 818 04", 8)
+func ParseCode(input string, digits uint) (Code, error) {
+	replacer := strings.NewReplacer(" ", "", "-", "")
+	cleaned := replacer.Replace(strings.TrimSpace(input))
+
+	if uint(len(cleaned)) != digits {
+		return Code{}, fmt.Errorf("%w: expected %d digits, got %q", ErrInvalidCode, digits, input)
+	}
+
+	for _, character := range cleaned {
+		if character < '0' || character > '9' {
+			return Code{}, fmt.Errorf("%w: %q contains a non digit character", ErrInvalidCode, input)
+		}
+	}
+
+	value, err := strconv.ParseUint(cleaned, 10, 32)
+	if err != nil {
+		return Code{}, fmt.Errorf("%w: %v", ErrInvalidCode, err)
+	}
+
+	return NewCode(uint32(value), digits), nil
+}
+
+// Parse a code entered by a user, that is encoded with the alphabet.
+// Spaces and dashes, used to group the characters, are ignored.
+// Lowercase characters are accepted for alphabets with uppercase letters.
+func ParseCodeWithAlphabet(input string, digits uint, alphabet string) (Code, error) {
+	alphabet, err := normalizeAlphabet(alphabet)
+	if err != nil {
+		return Code{}, err
+	}
+
+	if alphabet == "" {
+		return ParseCode(input, digits)
+	}
+
+	replacer := strings.NewReplacer(" ", "", "-", "")
+	cleaned := replacer.Replace(strings.TrimSpace(input))
+
+	if uint(len(cleaned)) != digits {
+		return Code{}, fmt.Errorf("%w: expected %d characters, got %q", ErrInvalidCode, digits, input)
+	}
+
+	value := uint64(0)
+	for _, character := range cleaned {
+		position := strings.IndexRune(alphabet, character)
+		if position < 0 {
+			position = strings.IndexRune(alphabet, unicode.ToUpper(character))
+		}
+
+		if position < 0 {
+			return Code{}, fmt.Errorf("%w: %q is not part of the alphabet", ErrInvalidCode, character)
+		}
+
+		value = value*uint64(len(alphabet)) + uint64(position)
+		if value > math.MaxUint32 {
+			return Code{}, fmt.Errorf("%w: %q is too large", ErrInvalidCode, input)
+		}
+	}
+
+	return Code{
+		value:    uint32(value),
+		digits:   digits,
+		alphabet: alphabet,
+	}, nil
+}
+
+func (c Code) Value() uint32 {
+	return c.value
+}
+
+func (c Code) Digits() uint {
+	return c.digits
+}
+
+// The alphabet of the characters of the code.
+func (c Code) Alphabet() string {
+	if c.alphabet == "" {
+		return Decimal
+	}
+
+	return c.alphabet
+}
+
+// The code prefixed with zeros up to its amount of digits.
+// Codes with an alphabet are prefixed with its first character.
+func (c Code) String() string {
+	if c.alphabet == "" {
+		return fmt.Sprintf("%0*d", c.digits, c.value)
+	}
+
+	base := uint32(len(c.alphabet))
+	value := c.value
+	characters := make([]byte, c.digits)
+
+	for index := len(characters) - 1; index >= 0; index-- {
+		characters[index] = c.alphabet[value%base]
+		value /= base
+	}
+
+	return string(characters)
+}
+
+// The code split into groups of size digits, separated by a space.
+//
+// Example:
+//
+//	NewCode(7081804, 8).Group(3) // "070 818 04"
+func (c Code) Group(size uint) string {
+	code := c.String()
+	if size == 0 {
+		return code
+	}
+
+	var builder strings.Builder
+	for index := 0; index < len(code); index += int(size) {
+		if index > 0 {
+			builder.WriteByte(' ')
+		}
+
+		end := index + int(size)
+		if end > len(code) {
+			end = len(code)
+		}
+
+		builder.WriteString(code[index:end])
+	}
+
+	return builder.String()
+}
+
+// Compare two codes in constant time.
+// The alphabet is public, so it is compared directly.
+func (c Code) Equal(other Code) bool {
+	isSameValue := subtle.ConstantTimeEq(int32(c.value), int32(other.value))
+	isSameLength := subtle.ConstantTimeEq(int32(c.digits), int32(other.digits))
+	return isSameValue&isSameLength == 1 && c.alphabet == other.alphabet
+}
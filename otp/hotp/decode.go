@@ -0,0 +1,218 @@
+package hotp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"bode.fun/otp/uri"
+)
+
+// Encoding of a secret, that is entered by a user.
+type Encoding string
+
+const (
+	// Detect the encoding of the secret. See DetectEncoding for details.
+	EncodingAuto Encoding = "auto"
+
+	// Base32, as used by otpauth Key URIs and most authenticator apps.
+	EncodingBase32 Encoding = "base32"
+
+	// Hex, as used by oathtool and the seed sheets of hardware tokens.
+	EncodingHex Encoding = "hex"
+
+	// Standard or URL base64, with or without padding.
+	EncodingBase64 Encoding = "base64"
+)
+
+var (
+	ErrInvalidSecret     = errors.New("invalid secret")
+	ErrInvalidEncoding   = errors.New("invalid secret encoding")
+	ErrAmbiguousEncoding = errors.New("ambiguous secret encoding")
+)
+
+// The characters of the encodings, as accepted by the decode functions.
+const (
+	base32Characters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz234567"
+	hexCharacters    = "0123456789ABCDEFabcdef"
+	base64Characters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_"
+)
+
+// Parse the name of an encoding case-insensitively.
+func ParseEncoding(name string) (Encoding, error) {
+	encoding := Encoding(strings.ToLower(name))
+
+	switch encoding {
+	case EncodingAuto, EncodingBase32, EncodingHex, EncodingBase64:
+		return encoding, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidEncoding, name)
+	}
+}
+
+// Decode a secret in the given encoding.
+// Whitespace is ignored for all encodings.
+func DecodeSecret(secret string, encoding Encoding) ([]byte, error) {
+	if encoding == EncodingAuto {
+		var err error
+		encoding, err = DetectEncoding(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch encoding {
+	case EncodingBase32:
+		return DecodeBase32(secret)
+	case EncodingHex:
+		return DecodeHex(secret)
+	case EncodingBase64:
+		return DecodeBase64(secret)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEncoding, encoding)
+	}
+}
+
+// Detect the encoding of a secret by its characters, length and padding.
+//
+// Hex secrets with a 0x prefix are always detected as hex. Secrets, that
+// are neither base32 nor hex, are detected as base64. As unpadded base32
+// is valid base64 as well, base64 is only considered next to base32, if
+// the secret has the padding of base64.
+//
+// If more than one encoding is plausible, like for a hex secret, that
+// only consists of the letters a to f and the digits 2 to 7, an error
+// wrapping ErrAmbiguousEncoding is returned. Pass the encoding explicitly
+// in that case.
+func DetectEncoding(secret string) (Encoding, error) {
+	secret = removeCharacters(secret, "")
+
+	if strings.HasPrefix(secret, "0x") || strings.HasPrefix(secret, "0X") {
+		return EncodingHex, nil
+	}
+
+	plausible := []Encoding{}
+	if isBase32(secret) {
+		plausible = append(plausible, EncodingBase32)
+	}
+
+	if isHex(secret) {
+		plausible = append(plausible, EncodingHex)
+	}
+
+	if isPaddedBase64(secret) || len(plausible) == 0 {
+		plausible = append(plausible, EncodingBase64)
+	}
+
+	if len(plausible) > 1 {
+		names := make([]string, 0, len(plausible))
+		for _, encoding := range plausible {
+			names = append(names, string(encoding))
+		}
+
+		return "", fmt.Errorf("%w: the secret is valid %s", ErrAmbiguousEncoding, strings.Join(names, " and "))
+	}
+
+	return plausible[0], nil
+}
+
+// Whether the secret is base32 with a length, that unpadded base32 can
+// have. Dashes and padding are ignored like by DecodeBase32.
+func isBase32(secret string) bool {
+	secret = strings.TrimRight(removeCharacters(secret, "-"), "=")
+
+	switch len(secret) % 8 {
+	case 1, 3, 6:
+		return false
+	}
+
+	return secret != "" && consistsOf(secret, base32Characters)
+}
+
+// Whether the secret is hex with an even length.
+// Dashes and colons are ignored like by DecodeHex.
+func isHex(secret string) bool {
+	secret = removeCharacters(secret, "-:")
+
+	return secret != "" && len(secret)%2 == 0 && consistsOf(secret, hexCharacters)
+}
+
+// Whether the secret is base64, that ends with the padding,
+// that matches its length.
+func isPaddedBase64(secret string) bool {
+	unpadded := strings.TrimRight(secret, "=")
+	padding := len(secret) - len(unpadded)
+
+	return padding > 0 && len(secret)%4 == 0 && padding == (4-len(unpadded)%4)%4 &&
+		consistsOf(unpadded, base64Characters)
+}
+
+// Decode a base32 secret, that was formatted for humans.
+// Case, whitespace, dashes and padding are ignored,
+// so "jbsw y3dp-ehpk 3pxp" is accepted.
+func DecodeBase32(secret string) ([]byte, error) {
+	secret = strings.TrimRight(removeCharacters(secret, "-"), "=")
+
+	decodedSecret, err := uri.SecretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return nil, fmt.Errorf("%w: base32: %v", ErrInvalidSecret, err)
+	}
+
+	return decodedSecret, nil
+}
+
+// Decode a hex secret.
+// Case, whitespace, dashes, colons and a 0x prefix are ignored.
+func DecodeHex(secret string) ([]byte, error) {
+	secret = removeCharacters(secret, "-:")
+	secret = strings.TrimPrefix(strings.TrimPrefix(secret, "0x"), "0X")
+
+	decodedSecret, err := hex.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: hex: %v", ErrInvalidSecret, err)
+	}
+
+	return decodedSecret, nil
+}
+
+// Decode a standard or URL base64 secret, with or without padding.
+// Whitespace is ignored.
+func DecodeBase64(secret string) ([]byte, error) {
+	secret = strings.TrimRight(removeCharacters(secret, ""), "=")
+
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(secret, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+
+	decodedSecret, err := encoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base64: %v", ErrInvalidSecret, err)
+	}
+
+	return decodedSecret, nil
+}
+
+// Remove whitespace and the given characters from the secret.
+func removeCharacters(secret string, characters string) string {
+	return strings.Map(func(character rune) rune {
+		if unicode.IsSpace(character) || strings.ContainsRune(characters, character) {
+			return -1
+		}
+
+		return character
+	}, secret)
+}
+
+func consistsOf(secret string, characters string) bool {
+	for _, character := range secret {
+		if !strings.ContainsRune(characters, character) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,138 @@
+package hotp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"bode.fun/otp/uri"
+)
+
+func (a Algorithm) MarshalText() ([]byte, error) {
+	return []byte(a), nil
+}
+
+// Parse the algorithm case-insensitively.
+func (a *Algorithm) UnmarshalText(text []byte) error {
+	algorithm, err := ParseAlgorithm(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = algorithm
+	return nil
+}
+
+// The text form of a Hotp instance is its otpauth url.
+//
+// A Hotp instance does not track a counter, so the text form carries no
+// counter. The url contains counter=0 only because the spec requires the
+// parameter, and UnmarshalText ignores it. Use otp.Key to store the
+// counter together with the key.
+//
+// A destroyed instance returns ErrDestroyed.
+func (h *Hotp) MarshalText() ([]byte, error) {
+	if h.IsDestroyed() {
+		return nil, ErrDestroyed
+	}
+
+	return []byte(h.ToUrl(0)), nil
+}
+
+// Parse an otpauth url into the instance.
+// The counter of the url is ignored, see MarshalText.
+func (h *Hotp) UnmarshalText(text []byte) error {
+	parsed, _, err := NewFromUrl(string(text))
+	if err != nil {
+		return err
+	}
+
+	*h = *parsed
+	return nil
+}
+
+// The JSON form of a Hotp instance.
+//
+// Example:
+//
+//	{
+//		"type": "hotp",
+//		"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+//		"algorithm": "sha1",
+//		"digits": 6,
+//		"account": "alice@example.com",
+//		"issuer": "Example",
+//		"offset": 4,
+//		"alphabet": "0123456789ABCDEF",
+//		"extensions": {"image": ["https://example.com/logo.png"]}
+//	}
+//
+// The secret is base32 encoded without padding. Account, issuer, offset,
+// alphabet and extensions are omitted, if they are empty. Like the text
+// form, the JSON form carries no counter.
+type jsonHotp struct {
+	Type       string     `json:"type"`
+	Secret     string     `json:"secret"`
+	Algorithm  Algorithm  `json:"algorithm"`
+	Digits     uint       `json:"digits"`
+	Account    string     `json:"account,omitempty"`
+	Issuer     string     `json:"issuer,omitempty"`
+	Offset     *uint8     `json:"offset,omitempty"`
+	Alphabet   string     `json:"alphabet,omitempty"`
+	Extensions url.Values `json:"extensions,omitempty"`
+}
+
+const jsonType = "hotp"
+
+// A destroyed instance returns ErrDestroyed.
+func (h *Hotp) MarshalJSON() ([]byte, error) {
+	if h.IsDestroyed() {
+		return nil, ErrDestroyed
+	}
+
+	return json.Marshal(jsonHotp{
+		Type:       jsonType,
+		Secret:     uri.SecretEncoding.EncodeToString(h.secret),
+		Algorithm:  h.algorithm,
+		Digits:     h.digits,
+		Account:    h.account,
+		Issuer:     h.issuer,
+		Offset:     h.offset,
+		Alphabet:   h.alphabet,
+		Extensions: h.extensions,
+	})
+}
+
+func (h *Hotp) UnmarshalJSON(data []byte) error {
+	var key jsonHotp
+
+	err := json.Unmarshal(data, &key)
+	if err != nil {
+		return err
+	}
+
+	if key.Type != jsonType {
+		return fmt.Errorf("can't read a %q key as %q", key.Type, jsonType)
+	}
+
+	hotpOptions := []HotpOption{
+		WithAlgorithm(key.Algorithm),
+		WithDigits(key.Digits),
+		WithAccount(key.Account),
+		WithIssuer(key.Issuer),
+		WithAlphabet(key.Alphabet),
+		WithExtensions(key.Extensions),
+	}
+
+	if key.Offset != nil {
+		hotpOptions = append(hotpOptions, WithTruncationOffset(*key.Offset))
+	}
+
+	parsed, err := NewFromBase32(key.Secret, hotpOptions...)
+	if err != nil {
+		return err
+	}
+
+	*h = *parsed
+	return nil
+}
@@ -0,0 +1,90 @@
+package hotp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The min size of the fingerprint key in bytes.
+const MinFingerprintKeySize = 16
+
+var ErrFingerprintKeyTooShort = errors.New("fingerprint key is too short")
+
+// The amount of bytes of the fingerprint, that are shown to humans.
+const fingerprintShortSize = 10
+
+// Fingerprint identifies the secret, the algorithm, the digits and the
+// period of a token, without revealing the secret. Tokens with the same
+// fingerprint share these values. The truncation offset, the alphabet
+// and the epoch are not part of the fingerprint.
+type Fingerprint [sha256.Size]byte
+
+// Calculate the fingerprint of a secret and the parameters of a token.
+// The period is 0 for hotp tokens.
+//
+// The fingerprint is a HMAC-SHA256 over all values, keyed with a secret
+// key of the caller, like a random key per store. Without the key,
+// fingerprints can't be used to brute-force low entropy secrets.
+// Fingerprints are only comparable, if they use the same key.
+func NewFingerprint(key []byte, secret []byte, algorithm Algorithm, digits uint, period uint) (Fingerprint, error) {
+	if len(key) < MinFingerprintKeySize {
+		return Fingerprint{}, fmt.Errorf("%w: %d bytes is less than %d bytes", ErrFingerprintKeyTooShort, len(key), MinFingerprintKeySize)
+	}
+
+	mac := hmac.New(sha256.New, key)
+
+	// Every value is prefixed by its length, so the values can't overlap
+	var buffer [8]byte
+	for _, value := range [][]byte{secret, []byte(strings.ToLower(string(algorithm)))} {
+		binary.BigEndian.PutUint64(buffer[:], uint64(len(value)))
+		mac.Write(buffer[:])
+		mac.Write(value)
+	}
+
+	binary.BigEndian.PutUint64(buffer[:], uint64(digits))
+	mac.Write(buffer[:])
+	binary.BigEndian.PutUint64(buffer[:], uint64(period))
+	mac.Write(buffer[:])
+
+	var fingerprint Fingerprint
+	mac.Sum(fingerprint[:0])
+
+	return fingerprint, nil
+}
+
+// The fingerprint of the secret, the algorithm and the digits,
+// keyed with the key. See NewFingerprint for details.
+// A destroyed instance returns ErrDestroyed.
+func (h *Hotp) Fingerprint(key []byte) (Fingerprint, error) {
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	if h.mac.destroyed {
+		return Fingerprint{}, ErrDestroyed
+	}
+
+	return NewFingerprint(key, h.secret, h.algorithm, h.digits, 0)
+}
+
+// The short form for humans, like "ABCD-EFGH-IJKL-MNOP".
+// It contains the first 80 bits of the fingerprint.
+func (f Fingerprint) String() string {
+	encoded := base32.StdEncoding.EncodeToString(f[:fingerprintShortSize])
+
+	groups := make([]string, 0, len(encoded)/4)
+	for start := 0; start < len(encoded); start += 4 {
+		groups = append(groups, encoded[start:start+4])
+	}
+
+	return strings.Join(groups, "-")
+}
+
+// Equal compares the fingerprints in constant time.
+func (f Fingerprint) Equal(other Fingerprint) bool {
+	return hmac.Equal(f[:], other[:])
+}
@@ -0,0 +1,57 @@
+package hotp
+
+import (
+	"crypto/rand"
+
+	"bode.fun/otp/uri"
+)
+
+// Generate a random secret of the given size using crypto/rand.
+func GenerateSecret(size uint) ([]byte, error) {
+	secret := make([]byte, size)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// Create a Hotp instance with a random secret.
+// The size of the secret is the recommended size for the algorithm.
+// Next to the instance, the unpadded base32 encoded secret is returned.
+//
+// Example:
+//
+//	hotp, encodedSecret, err := Generate(
+//				WithAlgorithm(Sha256),
+//				WithDigits(8),
+//			)
+func Generate(options ...HotpOption) (*Hotp, string, error) {
+	opts := &hotpOptions{
+		algorithm: defaultAlgorithm,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	algorithm, err := ParseAlgorithm(string(opts.algorithm))
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := GenerateSecret(algorithm.SecretSize())
+	if err != nil {
+		return nil, "", err
+	}
+	defer Zero(secret)
+
+	hotp, err := New(secret, options...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return hotp, uri.SecretEncoding.EncodeToString(secret), nil
+}
@@ -1,21 +1,12 @@
-// TODO: Add Secret validation
-// TODO: Add otp verification
-// TODO: Add verification against a window
 package hotp
 
 import (
-	"crypto/hmac"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
-	"encoding/base32"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"hash"
-	"math"
 	"net/url"
-	"strconv"
-	"strings"
+
+	"bode.fun/otp/uri"
 )
 
 type Algorithm string
@@ -26,24 +17,22 @@ const (
 	Sha512 Algorithm = "sha512"
 )
 
-func (a Algorithm) ToHashFunction() func() hash.Hash {
-	switch a {
-	case Sha1:
-		return sha1.New
-	case Sha256:
-		return sha256.New
-	case Sha512:
-		return sha512.New
-	default:
-		return nil
-	}
-}
+var (
+	ErrInvalidAlgorithm = errors.New("invalid hash algorithm")
+	ErrInvalidDigits    = errors.New("invalid amount of digits")
+	ErrSecretTooShort   = errors.New("secret is too short")
+)
 
 type hotpOptions struct {
-	algorithm Algorithm
-	digits    uint
-	account   string
-	issuer    string
+	algorithm    Algorithm
+	digits       uint
+	account      string
+	issuer       string
+	extensions   url.Values
+	offset       *uint8
+	alphabet     string
+	policy       *Policy
+	lockedMemory bool
 }
 
 type HotpOption func(*hotpOptions)
@@ -72,8 +61,29 @@ func WithIssuer(issuer string) HotpOption {
 	}
 }
 
+// Keep additional parameters of the Key URI, like the image or color
+// parameters of authenticator apps, so they are written back by ToUrl.
+func WithExtensions(extensions url.Values) HotpOption {
+	return func(ho *hotpOptions) {
+		ho.extensions = cloneValues(extensions)
+	}
+}
+
 const defaultDigits uint = 6
 
+// The range of digits a code can have.
+// RFC 4226 requires at least 6 digits and the truncated
+// 31 bit value can not have more than 10 digits.
+const (
+	MinDigits uint = 6
+	MaxDigits uint = 10
+)
+
+// The minimum size of a secret in bytes.
+// RFC 4226 asks for 128 bits, but a lot of services still
+// hand out 80 bit secrets, so those are accepted as well.
+const MinSecretSize = 10
+
 var defaultAlgorithm Algorithm = Sha1
 
 // Hotp is a stateful counter based One Time Password algorithm.
@@ -81,23 +91,37 @@ var defaultAlgorithm Algorithm = Sha1
 // It uses a counter to verify the user. This counter has to be stored on
 // the server and the client.
 type Hotp struct {
-	secret    []byte
-	algorithm Algorithm
-	digits    uint
-	account   string
-	issuer    string
+	secret     []byte
+	memory     []byte
+	algorithm  Algorithm
+	digits     uint
+	account    string
+	issuer     string
+	extensions url.Values
+	offset     *uint8
+	alphabet   string
+	modulus    uint32
+	policy     *Policy
+	mac        *keyedMac
 }
 
 // Create a Hotp instance from a unencoded secret.
 // The algorithm, that is usually used, is sha1.
 //
+// An error is returned, if the algorithm is unknown, the amount of digits
+// is out of range, the secret is shorter than MinSecretSize, the
+// truncation offset or alphabet are invalid or the key violates the policy.
+//
+// The secret is copied, so the caller can zero its slice afterwards.
+// Call Destroy to zero the copy, once the instance is no longer needed.
+//
 // Example:
 //
-//	hotp := New([]byte("12345678901234567890"),
-//				WithAlgorithm(sha1.New),
+//	hotp, err := New([]byte("12345678901234567890"),
+//				WithAlgorithm(Sha1),
 //				WithDigits(6),
 //			)
-func New(secret []byte, options ...HotpOption) *Hotp {
+func New(secret []byte, options ...HotpOption) (*Hotp, error) {
 	opts := &hotpOptions{
 		algorithm: defaultAlgorithm,
 		digits:    defaultDigits,
@@ -107,51 +131,108 @@ func New(secret []byte, options ...HotpOption) *Hotp {
 		option(opts)
 	}
 
-	return &Hotp{
-		secret:    secret,
-		algorithm: opts.algorithm,
-		digits:    opts.digits,
-		account:   opts.account,
-		issuer:    opts.issuer,
+	algorithm, err := ParseAlgorithm(string(opts.algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	hashFunction, err := algorithm.ToHashFunction()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.digits < MinDigits || opts.digits > MaxDigits {
+		return nil, fmt.Errorf("%w: %d is not between %d and %d", ErrInvalidDigits, opts.digits, MinDigits, MaxDigits)
 	}
+
+	if len(secret) < MinSecretSize {
+		return nil, fmt.Errorf("%w: %d bytes is less than %d bytes", ErrSecretTooShort, len(secret), MinSecretSize)
+	}
+
+	err = validateOffset(opts.offset, hashFunction().Size())
+	if err != nil {
+		return nil, err
+	}
+
+	alphabet, err := normalizeAlphabet(opts.alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	base := len(Decimal)
+	if alphabet != "" {
+		base = len(alphabet)
+	}
+
+	hotp := &Hotp{
+		algorithm:  algorithm,
+		digits:     opts.digits,
+		account:    opts.account,
+		issuer:     opts.issuer,
+		extensions: opts.extensions,
+		offset:     opts.offset,
+		alphabet:   alphabet,
+		modulus:    calculateModulus(opts.digits, base),
+		policy:     opts.policy,
+	}
+
+	err = hotp.storeSecret(secret, hashFunction, opts.lockedMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.policy != nil {
+		err = opts.policy.Validate(hotp)
+		if err != nil {
+			hotp.Destroy()
+			return nil, err
+		}
+	}
+
+	return hotp, nil
 }
 
 // Create a Hotp instance from a base32 encoded secret.
 // The algorithm, that is usually used, is sha1.
+// See DecodeBase32 for the accepted formatting of the secret.
 //
 // Example:
 //
-//	hotp := NewFromBase32("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
-//				WithAlgorithm(sha1.New),
+//	hotp, err := NewFromBase32("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+//				WithAlgorithm(Sha1),
 //				WithDigits(6),
 //			)
 func NewFromBase32(secret string, options ...HotpOption) (*Hotp, error) {
-	// Usually strings, used for hotp, do not contain padding
-	hasPadding := strings.Contains(secret, "=")
-	padding := base32.NoPadding
-
-	if hasPadding {
-		padding = base32.StdPadding
-	}
-
-	// Decode the secret
-	decodedSecret, err := base32.StdEncoding.
-		WithPadding(padding).
-		DecodeString(strings.ToUpper(secret))
+	return NewFromEncoded(secret, EncodingBase32, options...)
+}
 
+// Create a Hotp instance from an encoded secret.
+// Use EncodingAuto to detect the encoding of the secret.
+//
+// Example:
+//
+//	hotp, err := NewFromEncoded("3132333435363738393031323334353637383930", EncodingHex)
+func NewFromEncoded(secret string, encoding Encoding, options ...HotpOption) (*Hotp, error) {
+	decodedSecret, err := DecodeSecret(secret, encoding)
 	if err != nil {
 		return nil, err
 	}
 
-	return New(decodedSecret, options...), nil
+	defer Zero(decodedSecret)
+
+	return New(decodedSecret, options...)
 }
 
 func (h *Hotp) Digits() uint {
 	return h.digits
 }
 
+// A copy of the unencoded secret.
+// The caller is responsible for zeroing it after use.
 func (h *Hotp) Secret() []byte {
-	return h.secret
+	secret := make([]byte, len(h.secret))
+	copy(secret, h.secret)
+	return secret
 }
 
 func (h *Hotp) Algorithm() Algorithm {
@@ -166,136 +247,149 @@ func (h *Hotp) Issuer() string {
 	return h.issuer
 }
 
-func (h *Hotp) Label() string {
-	label := h.Account()
+// A copy of the additional parameters of the Key URI.
+func (h *Hotp) Extensions() url.Values {
+	return cloneValues(h.extensions)
+}
 
-	if h.Issuer() != "" {
-		label = label + ":" + h.Issuer()
+// The escaped label of the Key URI in the form issuer:account.
+func (h *Hotp) Label() string {
+	key := &uri.Key{
+		Issuer:  h.Issuer(),
+		Account: h.Account(),
 	}
 
-	return url.PathEscape(label)
+	return url.PathEscape(key.Label())
 }
 
 // Calculates the Hotp code, taking a counter as moving factor.
-//
-// TODO: Maybe change the output to a string and prepend the result with 0s
-func (h *Hotp) Calculate(movingFactor uint64) uint32 {
-	digest := calculateDigest(movingFactor, h.algorithm, h.secret)
-	offset := calculateOffset(digest)
-	fullCode := encodeDigest(digest, offset)
-	return shortenCodeToDigits(fullCode, h.digits)
-}
+// The zero Code is returned, if the instance is destroyed.
+func (h *Hotp) Calculate(movingFactor uint64) Code {
+	h.mac.Lock()
+	defer h.mac.Unlock()
 
-// Calculates the Hotp code, taking a counter as moving factor.
-// It uses a custom offset to extract 4 bytes from the HMAC-SHA Digest.
-// Keep in mind that the max value of the offset is the last index of the
-// resulting digest minus four bytes.
-// Therefore, the offset has to be between (inclusive) 0 and 16 for SHA1 (20 byte digest),
-// 28 for SHA256 (32 byte digest) and 60 for SHA512 (64 byte digest).
-//
-// TODO: Decide if this should be exposed
-func (h *Hotp) calculateCustomOffset(movingFactor uint64, offset uint8) uint32 {
-	digest := calculateDigest(movingFactor, h.algorithm, h.secret)
-	fullCode := encodeDigest(digest, offset)
-	return shortenCodeToDigits(fullCode, h.digits)
+	return h.calculate(movingFactor)
 }
 
-// TODO: Add tests
-// References: https://docs.yubico.com/yesdk/users-manual/application-oath/uri-string-format.html
-func (h *Hotp) ToUrl(counter uint64) string {
-	label := h.Account()
-
-	if h.Issuer() != "" {
-		label = label + ":" + h.Issuer()
+// Calculates the Hotp code like Calculate.
+// The lock of the keyed mac has to be held by the caller.
+func (h *Hotp) calculate(movingFactor uint64) Code {
+	if h.mac.destroyed {
+		return Code{}
 	}
 
-	otpUrl := &url.URL{
-		Scheme: "otpauth",
-		Host:   "hotp",
-		Path:   label,
-	}
+	digest := h.mac.sum(movingFactor)
 
-	encodedSecret := base32.StdEncoding.EncodeToString(h.Secret())
-
-	query := otpUrl.Query()
+	offset := calculateOffset(digest)
+	if h.offset != nil {
+		offset = *h.offset
+	}
 
-	query.Set("secret", encodedSecret)
-	query.Set("counter", fmt.Sprint(counter))
+	fullCode := encodeDigest(digest, offset)
+	return Code{
+		value:    shortenCode(fullCode, h.modulus),
+		digits:   h.digits,
+		alphabet: h.alphabet,
+	}
+}
 
-	query.Set("algorithm", string(h.Algorithm()))
+// Format the instance as otpauth Key URI together with the counter.
+// The secret of a destroyed instance is empty.
+func (h *Hotp) ToUrl(counter uint64) string {
+	key := &uri.Key{
+		Type:       uri.TypeHotp,
+		Issuer:     h.Issuer(),
+		Account:    h.Account(),
+		Secret:     h.secret,
+		Algorithm:  string(h.Algorithm()),
+		Digits:     h.Digits(),
+		Counter:    counter,
+		Offset:     h.offset,
+		Alphabet:   h.alphabet,
+		Extensions: h.extensions,
+	}
 
-	query.Set("digits", fmt.Sprint(h.Digits()))
+	return key.String()
+}
 
-	if h.Issuer() != "" {
-		query.Set("issuer", h.Issuer())
+// Create a Hotp instance and its counter from an otpauth url.
+// Legacy urls, with the issuer after the account, are accepted as well.
+// The options are applied after the values of the url.
+func NewFromUrl(rawUrl string, options ...HotpOption) (*Hotp, uint64, error) {
+	key, err := uri.ParseLenient(rawUrl)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer Zero(key.Secret)
 
-	otpUrl.RawQuery = query.Encode()
-
-	return otpUrl.String()
+	return NewFromKey(key, options...)
 }
 
-// TODO: Add some tests
-func NewFromUrl(rawUrl string) (*Hotp, uint64, error) {
-	var counter uint64
+// Create a Hotp instance and its counter from a parsed otpauth url.
+// The options are applied after the values of the key.
+//
+// The secret of the key is copied, so the caller can zero it afterwards.
+func NewFromKey(key *uri.Key, options ...HotpOption) (*Hotp, uint64, error) {
+	if key.Type != uri.TypeHotp {
+		return nil, 0, fmt.Errorf("%w: expected %q, got %q", uri.ErrInvalidType, uri.TypeHotp, key.Type)
+	}
 
-	otpUrl, err := url.Parse(rawUrl)
+	hotpOptions, err := optionsFromKey(key)
 	if err != nil {
-		return nil, counter, err
+		return nil, 0, err
 	}
 
-	encodedSecret := otpUrl.Query().Get("secret")
+	hotpOptions = append(hotpOptions, options...)
 
-	hotpOptions := []HotpOption{}
+	hotpInstance, err := New(key.Secret, hotpOptions...)
+	return hotpInstance, key.Counter, err
+}
 
-	label := otpUrl.Path
-	label = strings.TrimPrefix(label, "/")
-	account, _, _ := strings.Cut(label, ":")
-	if account != "" {
-		hotpOptions = append(hotpOptions, WithAccount(account))
+// The options, that are described by the parameters of the Key URI.
+// Parameters, that are not set, are left out so the defaults apply.
+func optionsFromKey(key *uri.Key) ([]HotpOption, error) {
+	hotpOptions := []HotpOption{
+		WithAccount(key.Account),
+		WithIssuer(key.Issuer),
+		WithExtensions(key.Extensions),
 	}
 
-	counterAsString := otpUrl.Query().Get("counter")
-	if counterAsString != "" {
-		counterAsInt, err := strconv.Atoi(counterAsString)
-		if err != nil {
-			return nil, counter, err
-		}
+	if key.Digits != 0 {
+		hotpOptions = append(hotpOptions, WithDigits(key.Digits))
+	}
+
+	if key.Offset != nil {
+		hotpOptions = append(hotpOptions, WithTruncationOffset(*key.Offset))
+	}
 
-		counter = uint64(counterAsInt)
+	if key.Alphabet != "" {
+		hotpOptions = append(hotpOptions, WithAlphabet(key.Alphabet))
 	}
 
-	digitsAsString := otpUrl.Query().Get("digits")
-	if digitsAsString != "" {
-		digitsAsInt, err := strconv.Atoi(digitsAsString)
+	if key.Algorithm != "" {
+		algorithm, err := ParseAlgorithm(key.Algorithm)
 		if err != nil {
-			return nil, counter, err
+			return nil, err
 		}
 
-		digits := uint(digitsAsInt)
-		hotpOptions = append(hotpOptions, WithDigits(digits))
+		hotpOptions = append(hotpOptions, WithAlgorithm(algorithm))
 	}
 
-	issuer := otpUrl.Query().Get("issuer")
-	if issuer != "" {
-		hotpOptions = append(hotpOptions, WithIssuer(issuer))
-	}
+	return hotpOptions, nil
+}
 
-	algorithm := otpUrl.Query().Get("algorithm")
-	if algorithm == "" {
-		algorithm = string(Sha1)
+// Copy the values, so the instance does not share them with the caller
+func cloneValues(values url.Values) url.Values {
+	if values == nil {
+		return nil
 	}
-	hotpOptions = append(hotpOptions, WithAlgorithm(Algorithm(algorithm)))
 
-	hotpInstance, err := NewFromBase32(encodedSecret, hotpOptions...)
-	return hotpInstance, counter, err
-}
+	cloned := make(url.Values, len(values))
+	for name, value := range values {
+		cloned[name] = append([]string(nil), value...)
+	}
 
-// Calculate hmac digest of the moving Factor
-func calculateDigest(movingFactor uint64, algorithm Algorithm, secret []byte) []byte {
-	hmacInstance := hmac.New(algorithm.ToHashFunction(), secret)
-	binary.Write(hmacInstance, binary.BigEndian, movingFactor)
-	return hmacInstance.Sum(nil)
+	return cloned
 }
 
 // Encode the digest as a 31 bit uint32 using the provided offset
@@ -313,14 +407,14 @@ func calculateOffset(digest []byte) uint8 {
 	return lastByte & 0xF
 }
 
-// Shorten the code to the desired length.
-// The length od the calculated code can, by design, not be higher than 10 characters.
-// If the digit size is larger then 10, it just gets prefixed with zeros.
-func shortenCodeToDigits(fullCode uint32, digits uint) uint32 {
-	if digits < 10 {
-		modulusBase := uint32(math.Pow10(int(digits)))
-		return fullCode % modulusBase
+// Shorten the code to the desired length using the modulus of the digits.
+// The length of the calculated code can, by design, not be higher than 10
+// decimal characters. If the modulus is 0, the code already fits and just
+// gets prefixed with zeros.
+func shortenCode(fullCode uint32, modulus uint32) uint32 {
+	if modulus == 0 {
+		return fullCode
 	}
 
-	return fullCode
+	return fullCode % modulus
 }
@@ -1,6 +1,13 @@
 package hotp_test
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"bode.fun/otp/hotp"
@@ -13,57 +20,800 @@ import (
 // https://www.rfc-editor.org/rfc/rfc4226#page-32
 func Test_Rfc4226(t *testing.T) {
 	is := is.New(t)
-	h := hotp.New(
+	h, err := hotp.New(
 		[]byte("12345678901234567890"),
 	)
+	is.NoErr(err)
 
 	{
 		code := h.Calculate(0)
-		is.Equal(uint32(755224), code)
+		is.Equal("755224", code.String())
 	}
 
 	{
 		code := h.Calculate(1)
-		is.Equal(uint32(287082), code)
+		is.Equal("287082", code.String())
 	}
 
 	{
 		code := h.Calculate(2)
-		is.Equal(uint32(359152), code)
+		is.Equal("359152", code.String())
 	}
 
 	{
 		code := h.Calculate(3)
-		is.Equal(uint32(969429), code)
+		is.Equal("969429", code.String())
 	}
 
 	{
 		code := h.Calculate(4)
-		is.Equal(uint32(338314), code)
+		is.Equal("338314", code.String())
 	}
 
 	{
 		code := h.Calculate(5)
-		is.Equal(uint32(254676), code)
+		is.Equal("254676", code.String())
 	}
 
 	{
 		code := h.Calculate(6)
-		is.Equal(uint32(287922), code)
+		is.Equal("287922", code.String())
 	}
 
 	{
 		code := h.Calculate(7)
-		is.Equal(uint32(162583), code)
+		is.Equal("162583", code.String())
 	}
 
 	{
 		code := h.Calculate(8)
-		is.Equal(uint32(399871), code)
+		is.Equal("399871", code.String())
 	}
 
 	{
 		code := h.Calculate(9)
-		is.Equal(uint32(520489), code)
+		is.Equal("520489", code.String())
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New(
+		[]byte("12345678901234567890"),
+	)
+	is.NoErr(err)
+
+	{
+		counter, ok := h.Verify(hotp.NewCode(755224, 6), 0)
+		is.True(ok)
+		is.Equal(uint64(1), counter)
+	}
+
+	{
+		// The code for counter 5 is inside the default look-ahead window
+		counter, ok := h.Verify(hotp.NewCode(254676, 6), 1)
+		is.True(ok)
+		is.Equal(uint64(6), counter)
+	}
+
+	{
+		// Codes behind the stored counter are rejected
+		counter, ok := h.Verify(hotp.NewCode(755224, 6), 1)
+		is.True(!ok)
+		is.Equal(uint64(1), counter)
+	}
+
+	{
+		_, ok := h.Verify(hotp.NewCode(520489, 6), 0, hotp.WithLookAhead(8))
+		is.True(!ok)
+	}
+
+	{
+		counter, ok := h.Verify(hotp.NewCode(520489, 6), 0, hotp.WithLookAhead(9))
+		is.True(ok)
+		is.Equal(uint64(10), counter)
+	}
+}
+
+func Test_Resync(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New(
+		[]byte("12345678901234567890"),
+	)
+	is.NoErr(err)
+
+	{
+		counter, ok := h.Resync(hotp.NewCode(287922, 6), hotp.NewCode(162583, 6), 0, 100)
+		is.True(ok)
+		is.Equal(uint64(8), counter)
+	}
+
+	{
+		// The codes have to be consecutive
+		counter, ok := h.Resync(hotp.NewCode(287922, 6), hotp.NewCode(399871, 6), 0, 100)
+		is.True(!ok)
+		is.Equal(uint64(0), counter)
+	}
+
+	{
+		// The codes have to be inside the bound
+		_, ok := h.Resync(hotp.NewCode(287922, 6), hotp.NewCode(162583, 6), 0, 5)
+		is.True(!ok)
+	}
+
+	{
+		// Counter values behind the stored counter are never accepted
+		_, ok := h.Resync(hotp.NewCode(755224, 6), hotp.NewCode(287082, 6), 1, 100)
+		is.True(!ok)
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	is := is.New(t)
+
+	{
+		h, encodedSecret, err := hotp.Generate()
+		is.NoErr(err)
+		is.Equal(20, len(h.Secret()))
+		is.Equal(32, len(encodedSecret))
+		is.Equal(hotp.Sha1, h.Algorithm())
+
+		decoded, err := hotp.NewFromBase32(encodedSecret)
+		is.NoErr(err)
+		is.Equal(h.Secret(), decoded.Secret())
+	}
+
+	{
+		h, encodedSecret, err := hotp.Generate(hotp.WithAlgorithm(hotp.Sha512))
+		is.NoErr(err)
+		is.Equal(64, len(h.Secret()))
+		is.True(!strings.Contains(encodedSecret, "="))
+	}
+
+	{
+		first, _, err := hotp.Generate()
+		is.NoErr(err)
+		second, _, err := hotp.Generate()
+		is.NoErr(err)
+		is.True(string(first.Secret()) != string(second.Secret()))
+	}
+}
+
+func Test_Code(t *testing.T) {
+	is := is.New(t)
+
+	{
+		code := hotp.NewCode(7081804, 8)
+		is.Equal("07081804", code.String())
+		is.Equal("070 818 04", code.Group(3))
+		is.Equal("0708 1804", code.Group(4))
+		is.Equal("07081804", code.Group(0))
+	}
+
+	{
+		code, err := hotp.ParseCode(" 070 818-04 ", 8)
+		is.NoErr(err)
+		is.Equal(uint32(7081804), code.Value())
+		is.Equal(uint(8), code.Digits())
+		is.True(code.Equal(hotp.NewCode(7081804, 8)))
+		is.True(!code.Equal(hotp.NewCode(7081804, 7)))
+	}
+
+	{
+		_, err := hotp.ParseCode("7081804", 8)
+		is.True(errors.Is(err, hotp.ErrInvalidCode))
+	}
+
+	{
+		_, err := hotp.ParseCode("07O81804", 8)
+		is.True(errors.Is(err, hotp.ErrInvalidCode))
+	}
+}
+
+func Test_Validation(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	{
+		h, err := hotp.New(secret, hotp.WithAlgorithm("SHA1"))
+		is.NoErr(err)
+		is.Equal(hotp.Sha1, h.Algorithm())
+		is.Equal("755224", h.Calculate(0).String())
+	}
+
+	{
+		_, err := hotp.New(secret, hotp.WithAlgorithm("md5"))
+		is.True(errors.Is(err, hotp.ErrInvalidAlgorithm))
+	}
+
+	{
+		_, err := hotp.New(secret, hotp.WithDigits(5))
+		is.True(errors.Is(err, hotp.ErrInvalidDigits))
+	}
+
+	{
+		_, err := hotp.New(secret, hotp.WithDigits(11))
+		is.True(errors.Is(err, hotp.ErrInvalidDigits))
+	}
+
+	{
+		_, err := hotp.New([]byte("123456789"))
+		is.True(errors.Is(err, hotp.ErrSecretTooShort))
+	}
+
+	{
+		h, _, err := hotp.NewFromUrl("otpauth://hotp/account?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=SHA256")
+		is.NoErr(err)
+		is.Equal(hotp.Sha256, h.Algorithm())
+	}
+
+	{
+		_, _, err := hotp.NewFromUrl("otpauth://hotp/account?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=MD5")
+		is.True(errors.Is(err, hotp.ErrInvalidAlgorithm))
+	}
+}
+
+func Test_CalculateDoesNotAllocate(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New([]byte("12345678901234567890"))
+	is.NoErr(err)
+
+	code := hotp.NewCode(520489, 6)
+
+	is.Equal(0.0, testing.AllocsPerRun(100, func() {
+		h.Calculate(9)
+	}))
+
+	is.Equal(0.0, testing.AllocsPerRun(100, func() {
+		h.Verify(code, 0)
+	}))
+}
+
+func Benchmark_Calculate(b *testing.B) {
+	h, err := hotp.New([]byte("12345678901234567890"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.Calculate(uint64(i))
+	}
+}
+
+func Benchmark_Verify(b *testing.B) {
+	h, err := hotp.New([]byte("12345678901234567890"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// The code for counter 9 is at the end of the default window
+	code := hotp.NewCode(520489, 6)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		h.Verify(code, 0)
+	}
+}
+
+func Test_CalculateRange(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New([]byte("12345678901234567890"))
+	is.NoErr(err)
+
+	codes, err := h.CalculateRange(3, 4)
+	is.NoErr(err)
+	is.Equal(4, len(codes))
+
+	expected := []string{"969429", "338314", "254676", "287922"}
+	for index, counterCode := range codes {
+		is.Equal(uint64(3+index), counterCode.Counter)
+		is.Equal(expected[index], counterCode.Code.String())
+	}
+
+	codes, err = h.CalculateRange(0, 0)
+	is.NoErr(err)
+	is.Equal(0, len(codes))
+
+	codes, err = h.CalculateRange(^uint64(0), 5)
+	is.NoErr(err)
+	is.Equal(1, len(codes))
+
+	codes, err = h.CalculateRange(0, hotp.MaxRange)
+	is.NoErr(err)
+	is.Equal(hotp.MaxRange, len(codes))
+
+	_, err = h.CalculateRange(0, ^uint(0))
+	is.True(errors.Is(err, hotp.ErrRangeTooLarge))
+}
+
+func Test_SecretLifecycle(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	h, err := hotp.New(secret)
+	is.NoErr(err)
+
+	{
+		// Changing the returned secret does not change the instance
+		copied := h.Secret()
+		copied[0] = 0
+		is.Equal("755224", h.Calculate(0).String())
+	}
+
+	code := h.Calculate(3)
+
+	h.Destroy()
+	is.Equal(0, len(h.Secret()))
+	is.True(h.IsDestroyed())
+
+	// A destroyed instance neither calculates nor verifies codes
+	is.Equal(hotp.Code{}, h.Calculate(3))
+	is.True(!h.Check(code, 3))
+	is.True(!h.Check(hotp.Code{}, 3))
+
+	_, isValid := h.Verify(code, 0)
+	is.True(!isValid)
+
+	_, isValid = h.Resync(code, h.Calculate(4), 0, 10)
+	is.True(!isValid)
+
+	// A destroyed instance can't be serialized
+	_, err = h.MarshalText()
+	is.True(errors.Is(err, hotp.ErrDestroyed))
+
+	_, err = json.Marshal(h)
+	is.True(errors.Is(err, hotp.ErrDestroyed))
+
+	// The slice of the caller is left untouched
+	is.Equal("12345678901234567890", string(secret))
+}
+
+func Test_LockedMemory(t *testing.T) {
+	is := is.New(t)
+
+	h, err := hotp.New(
+		[]byte("12345678901234567890"),
+		hotp.WithLockedMemory(),
+	)
+	is.NoErr(err)
+	is.Equal("755224", h.Calculate(0).String())
+	is.Equal("12345678901234567890", string(h.Secret()))
+
+	is.NoErr(h.Close())
+	is.Equal(0, len(h.Secret()))
+}
+
+// Secrets, that are longer than the block size of the hash, are hashed
+// before they are padded. The codes must match the ones of crypto/hmac.
+func Test_LongSecret(t *testing.T) {
+	is := is.New(t)
+
+	for _, algorithm := range []hotp.Algorithm{hotp.Sha1, hotp.Sha256, hotp.Sha512} {
+		hashFunction, err := algorithm.ToHashFunction()
+		is.NoErr(err)
+
+		secret := []byte(strings.Repeat("12345678901234567890", 10))
+
+		h, err := hotp.New(secret, hotp.WithAlgorithm(algorithm), hotp.WithLockedMemory())
+		is.NoErr(err)
+
+		mac := hmac.New(hashFunction, secret)
+		mac.Write([]byte{0, 0, 0, 0, 0, 0, 0, 7})
+		digest := mac.Sum(nil)
+
+		offset := digest[len(digest)-1] & 0xf
+		value := binary.BigEndian.Uint32(digest[offset:]) & 0x7fffffff
+
+		is.Equal(hotp.NewCode(value%1000000, 6), h.Calculate(7))
+		is.NoErr(h.Close())
+	}
+}
+
+func Test_Encoding(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New(
+		[]byte("12345678901234567890"),
+		hotp.WithAlgorithm(hotp.Sha256),
+		hotp.WithAccount("alice@example.com"),
+		hotp.WithIssuer("Example"),
+	)
+	is.NoErr(err)
+
+	{
+		data, err := json.Marshal(h)
+		is.NoErr(err)
+		is.Equal(`{"type":"hotp","secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","algorithm":"sha256","digits":6,"account":"alice@example.com","issuer":"Example"}`, string(data))
+
+		var config struct {
+			Key *hotp.Hotp `json:"key"`
+		}
+		err = json.Unmarshal([]byte(`{"key":`+string(data)+`}`), &config)
+		is.NoErr(err)
+		is.Equal(h.Secret(), config.Key.Secret())
+		is.Equal(hotp.Sha256, config.Key.Algorithm())
+		is.Equal("alice@example.com", config.Key.Account())
+		is.Equal(h.Calculate(0), config.Key.Calculate(0))
+	}
+
+	{
+		text, err := h.MarshalText()
+		is.NoErr(err)
+
+		var parsed hotp.Hotp
+		is.NoErr(parsed.UnmarshalText(text))
+		is.Equal(h.Secret(), parsed.Secret())
+		is.Equal(h.Calculate(1), parsed.Calculate(1))
+	}
+
+	{
+		var algorithm hotp.Algorithm
+		is.NoErr(json.Unmarshal([]byte(`"SHA512"`), &algorithm))
+		is.Equal(hotp.Sha512, algorithm)
+
+		err := json.Unmarshal([]byte(`"MD5"`), &algorithm)
+		is.True(errors.Is(err, hotp.ErrInvalidAlgorithm))
+	}
+
+	{
+		var parsed hotp.Hotp
+		err := json.Unmarshal([]byte(`{"type":"totp","secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}`), &parsed)
+		is.True(err != nil)
+	}
+}
+
+func Test_Url(t *testing.T) {
+	is := is.New(t)
+	h, err := hotp.New(
+		[]byte("12345678901234567890"),
+		hotp.WithIssuer("Example"),
+		hotp.WithAccount("alice@example.com"),
+	)
+	is.NoErr(err)
+
+	parsed, counter, err := hotp.NewFromUrl(h.ToUrl(1 << 40))
+	is.NoErr(err)
+	is.Equal(uint64(1<<40), counter)
+	is.Equal("Example", parsed.Issuer())
+	is.Equal("alice@example.com", parsed.Account())
+	is.Equal(h.Calculate(7), parsed.Calculate(7))
+}
+
+func Test_RegisterAlgorithm(t *testing.T) {
+	is := is.New(t)
+
+	is.NoErr(hotp.RegisterAlgorithm("SHA384", sha512.New384))
+	t.Cleanup(func() {
+		is.NoErr(hotp.UnregisterAlgorithm("sha384"))
+	})
+
+	is.True(errors.Is(hotp.RegisterAlgorithm("sha1", sha512.New384), hotp.ErrInvalidAlgorithm))
+	is.True(errors.Is(hotp.RegisterAlgorithm("sha224", nil), hotp.ErrInvalidAlgorithm))
+	is.True(errors.Is(hotp.UnregisterAlgorithm("SHA256"), hotp.ErrInvalidAlgorithm))
+
+	// The digest is too short for the dynamic truncation
+	is.True(errors.Is(hotp.RegisterAlgorithm("md5", md5.New), hotp.ErrInvalidAlgorithm))
+	_, err := hotp.ParseAlgorithm("md5")
+	is.True(errors.Is(err, hotp.ErrInvalidAlgorithm))
+
+	algorithm, err := hotp.ParseAlgorithm("Sha384")
+	is.NoErr(err)
+	is.Equal(uint(48), algorithm.SecretSize())
+
+	h, err := hotp.New(
+		[]byte("123456789012345678901234567890123456789012345678"),
+		hotp.WithAlgorithm(algorithm),
+	)
+	is.NoErr(err)
+
+	parsed, _, err := hotp.NewFromUrl(h.ToUrl(0))
+	is.NoErr(err)
+	is.Equal(algorithm, parsed.Algorithm())
+	is.Equal(h.Calculate(3), parsed.Calculate(3))
+
+	{
+		_, err := hotp.Algorithm("sha3-512").ToHashFunction()
+		is.True(errors.Is(err, hotp.ErrInvalidAlgorithm))
+		is.Equal(uint(0), hotp.Algorithm("sha3-512").SecretSize())
+	}
+}
+
+func Test_Truncation(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	{
+		// The dynamic offset of the first digest of RFC 4226 is 0
+		h, err := hotp.New(secret, hotp.WithTruncationOffset(0))
+		is.NoErr(err)
+		is.Equal("755224", h.Calculate(0).String())
+
+		h, err = hotp.New(secret, hotp.WithTruncationOffset(4))
+		is.NoErr(err)
+		is.Equal("455891", h.Calculate(0).String())
+
+		offset, hasOffset := h.TruncationOffset()
+		is.True(hasOffset)
+		is.Equal(uint8(4), offset)
+	}
+
+	{
+		h, err := hotp.New(secret, hotp.WithAlphabet(hotp.Hexadecimal))
+		is.NoErr(err)
+		is.Equal("93CF18", h.Calculate(0).String())
+
+		code, err := h.ParseCode("93cf18")
+		is.NoErr(err)
+		is.True(h.Check(code, 0))
+
+		parsed, _, err := hotp.NewFromUrl(h.ToUrl(0))
+		is.NoErr(err)
+		is.Equal(hotp.Hexadecimal, parsed.Alphabet())
+		is.Equal(h.Calculate(5), parsed.Calculate(5))
+
+		data, err := json.Marshal(h)
+		is.NoErr(err)
+
+		var decoded hotp.Hotp
+		is.NoErr(json.Unmarshal(data, &decoded))
+		is.Equal(h.Calculate(5), decoded.Calculate(5))
+	}
+
+	{
+		h, err := hotp.New(secret, hotp.WithTruncationOffset(8), hotp.WithDigits(8))
+		is.NoErr(err)
+
+		parsed, _, err := hotp.NewFromUrl(h.ToUrl(0))
+		is.NoErr(err)
+		is.Equal(h.Calculate(9), parsed.Calculate(9))
+	}
+
+	{
+		_, err := hotp.New(secret, hotp.WithTruncationOffset(17))
+		is.True(errors.Is(err, hotp.ErrInvalidOffset))
+
+		_, err = hotp.New(secret, hotp.WithAlphabet("AA"))
+		is.True(errors.Is(err, hotp.ErrInvalidAlphabet))
+
+		_, err = hotp.ParseCodeWithAlphabet("93CF1G", 6, hotp.Hexadecimal)
+		is.True(errors.Is(err, hotp.ErrInvalidCode))
+	}
+}
+
+func Test_SecretEncodings(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	secrets := map[string]hotp.Encoding{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ":                            hotp.EncodingBase32,
+		"gezd gnbv-gy3t qojq gezd gnbv gy3t qojq":                     hotp.EncodingBase32,
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====":                        hotp.EncodingBase32,
+		"3132333435363738393031323334353637383930":                    hotp.EncodingHex,
+		"31 32 33 34 35 36 37 38 39 30 31 32 33 34 35 36 37 38 39 30": hotp.EncodingHex,
+		"0x3132333435363738393031323334353637383930":                  hotp.EncodingHex,
+		"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=":                                hotp.EncodingBase64,
+		"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA":                                 hotp.EncodingBase64,
+	}
+
+	for encodedSecret, encoding := range secrets {
+		detected, err := hotp.DetectEncoding(encodedSecret)
+		is.NoErr(err)
+		is.Equal(encoding, detected)
+
+		decodedSecret, err := hotp.DecodeSecret(encodedSecret, hotp.EncodingAuto)
+		is.NoErr(err)
+		is.Equal(secret, decodedSecret)
+
+		h, err := hotp.NewFromEncoded(encodedSecret, encoding)
+		is.NoErr(err)
+		is.Equal("755224", h.Calculate(0).String())
+	}
+
+	{
+		// Padded base64, that only consists of base32 characters
+		for _, encodedSecret := range []string{
+			"AAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+			"AAAAAAAAAAAAAAAAAAAAAA==",
+		} {
+			detected, err := hotp.DetectEncoding(encodedSecret)
+			is.NoErr(err)
+			is.Equal(hotp.EncodingBase64, detected)
+
+			decodedSecret, err := hotp.DecodeSecret(encodedSecret, hotp.EncodingAuto)
+			is.NoErr(err)
+			is.Equal(len(encodedSecret)/4*3-strings.Count(encodedSecret, "="), len(decodedSecret))
+		}
+
+		// Secrets, that are valid in more than one encoding
+		for _, encodedSecret := range []string{
+			"AAAAAAAAAAAAAAA=",
+			"deadbeefdeadbeef",
+		} {
+			_, err := hotp.DetectEncoding(encodedSecret)
+			is.True(errors.Is(err, hotp.ErrAmbiguousEncoding))
+
+			_, err = hotp.DecodeSecret(encodedSecret, hotp.EncodingAuto)
+			is.True(errors.Is(err, hotp.ErrAmbiguousEncoding))
+		}
+	}
+
+	{
+		h, err := hotp.NewFromBase32("gezd-gnbv gy3t-qojq gezd-gnbv gy3t-qojq")
+		is.NoErr(err)
+		is.Equal(secret, h.Secret())
+	}
+
+	{
+		_, err := hotp.DecodeSecret("3132333435363738393031323334353637383930", hotp.EncodingBase64)
+		is.NoErr(err)
+
+		_, err = hotp.DecodeHex("313")
+		is.True(errors.Is(err, hotp.ErrInvalidSecret))
+
+		_, err = hotp.NewFromEncoded("GEZDGNBV", "base58")
+		is.True(errors.Is(err, hotp.ErrInvalidEncoding))
+
+		_, err = hotp.ParseEncoding("base58")
+		is.True(errors.Is(err, hotp.ErrInvalidEncoding))
+
+		encoding, err := hotp.ParseEncoding("HEX")
+		is.NoErr(err)
+		is.Equal(hotp.EncodingHex, encoding)
+	}
+}
+
+func Test_Fingerprint(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+	key := []byte("0123456789abcdef")
+
+	h, err := hotp.New(secret, hotp.WithAccount("alice"))
+	is.NoErr(err)
+
+	fingerprint, err := h.Fingerprint(key)
+	is.NoErr(err)
+
+	// The fingerprint must be stable across versions
+	is.Equal("ZXDK-2F7B-R5DH-ZBFH", fingerprint.String())
+
+	{
+		// The label is not part of the fingerprint
+		other, err := hotp.New(secret, hotp.WithAccount("bob"), hotp.WithIssuer("Example"))
+		is.NoErr(err)
+
+		otherFingerprint, err := other.Fingerprint(key)
+		is.NoErr(err)
+		is.True(fingerprint.Equal(otherFingerprint))
+
+		// Fingerprints of different keys are not comparable
+		otherFingerprint, err = other.Fingerprint([]byte("fedcba9876543210"))
+		is.NoErr(err)
+		is.True(!fingerprint.Equal(otherFingerprint))
+	}
+
+	{
+		other, err := hotp.New(secret, hotp.WithDigits(8))
+		is.NoErr(err)
+
+		otherFingerprint, err := other.Fingerprint(key)
+		is.NoErr(err)
+		is.True(!fingerprint.Equal(otherFingerprint))
+	}
+
+	{
+		other, err := hotp.New([]byte("12345678901234567891"))
+		is.NoErr(err)
+
+		otherFingerprint, err := other.Fingerprint(key)
+		is.NoErr(err)
+		is.True(!fingerprint.Equal(otherFingerprint))
+	}
+
+	{
+		newFingerprint, err := hotp.NewFingerprint(key, secret, hotp.Sha1, 6, 0)
+		is.NoErr(err)
+		is.Equal(fingerprint, newFingerprint)
+
+		newFingerprint, err = hotp.NewFingerprint(key, secret, hotp.Sha1, 6, 30)
+		is.NoErr(err)
+		is.True(newFingerprint != fingerprint)
+	}
+
+	{
+		_, err := h.Fingerprint(nil)
+		is.True(errors.Is(err, hotp.ErrFingerprintKeyTooShort))
+	}
+
+	{
+		h.Destroy()
+		_, err := h.Fingerprint(key)
+		is.True(errors.Is(err, hotp.ErrDestroyed))
+	}
+}
+
+func Test_Policy(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	policy := hotp.Policy{
+		ForbiddenAlgorithms: []hotp.Algorithm{hotp.Sha1},
+		MinSecretBits:       256,
+		MinDigits:           8,
+		MaxWindow:           1,
+		MaxResyncWindow:     100,
+		MaxDrift:            1,
+	}
+
+	{
+		h, err := hotp.New(secret)
+		is.NoErr(err)
+
+		violations := policy.Check(h)
+		is.Equal(3, len(violations))
+		is.Equal(hotp.RuleAlgorithm, violations[0].Rule)
+		is.Equal(hotp.RuleSecret, violations[1].Rule)
+		is.Equal(hotp.RuleDigits, violations[2].Rule)
+
+		is.Equal(1, len(policy.CheckWindow(10)))
+		is.Equal(0, len(policy.CheckWindow(1)))
+		is.Equal(1, len(policy.CheckResyncWindow(1000)))
+		is.Equal(0, len(policy.CheckResyncWindow(100)))
+		is.Equal(1, len(policy.CheckDrift(-2)))
+		is.Equal(0, len(hotp.Policy{}.CheckDrift(-2)))
+	}
+
+	{
+		_, err := hotp.New(secret, hotp.WithPolicy(policy))
+		is.True(errors.Is(err, hotp.ErrPolicyViolation))
+		is.Equal("policy violation: algorithm: sha1 is forbidden; secret: 160 bits is less than 256 bits; digits: 6 digits is less than 8 digits", err.Error())
+	}
+
+	{
+		h, err := hotp.New(
+			[]byte("12345678901234567890123456789012"),
+			hotp.WithAlgorithm(hotp.Sha256),
+			hotp.WithDigits(8),
+			hotp.WithPolicy(policy),
+		)
+		is.NoErr(err)
+
+		// A larger look-ahead window than the policy allows is rejected
+		_, isValid := h.Verify(h.Calculate(1), 0, hotp.WithLookAhead(10))
+		is.True(!isValid)
+
+		_, isValid = h.Verify(h.Calculate(1), 0, hotp.WithLookAhead(1))
+		is.True(isValid)
+
+		// The default window is limited to the policy
+		_, isValid = h.Verify(h.Calculate(2), 0)
+		is.True(!isValid)
+
+		_, isValid = h.VerifyAt(h.Calculate(1), 0)
+		is.True(isValid)
+
+		// So is the bound of a resynchronisation
+		_, isValid = h.Resync(h.Calculate(50), h.Calculate(51), 0, 1000)
+		is.True(!isValid)
+
+		_, isValid = h.Resync(h.Calculate(50), h.Calculate(51), 0, 100)
+		is.True(isValid)
+	}
+
+	{
+		h, err := hotp.New(secret)
+		is.NoErr(err)
+
+		_, isValid := h.Verify(h.Calculate(2), 0, hotp.WithVerifyPolicy(policy))
+		is.True(!isValid)
+
+		_, isValid = h.Verify(h.Calculate(1), 0, hotp.WithLookAhead(5), hotp.WithVerifyPolicy(policy))
+		is.True(!isValid)
+
+		_, isValid = h.Verify(h.Calculate(2), 0)
+		is.True(isValid)
 	}
 }
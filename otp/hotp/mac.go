@@ -0,0 +1,142 @@
+package hotp
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"sync"
+)
+
+// The inner and outer padding of HMAC, see RFC 2104.
+const (
+	innerPad = 0x36
+	outerPad = 0x5c
+)
+
+// keyedMac calculates HMAC digests from a key schedule, that is derived
+// once per secret. This keeps the code calculation free of allocations.
+//
+// The key schedule consists of the padded keys and, if the hash function
+// supports it, the states of the inner and outer hash after hashing them.
+// It is stored in memory owned by the Hotp instance, so Destroy can zero it.
+//
+// The state is not safe for concurrent use, so the mutex has to be held
+// while calculating a digest.
+type keyedMac struct {
+	sync.Mutex
+	inner      hash.Hash
+	outer      hash.Hash
+	innerPad   []byte
+	outerPad   []byte
+	innerState []byte
+	outerState []byte
+	counter    [8]byte
+	digest     []byte
+	destroyed  bool
+}
+
+// The size of the key schedule of the hash function in bytes.
+func keyScheduleSize(hashFunction func() hash.Hash) int {
+	mac := hashFunction()
+
+	return 2*mac.BlockSize() + 2*marshaledStateSize(mac)
+}
+
+// The size of the marshaled state of the hash function,
+// or 0 if the state can't be restored.
+func marshaledStateSize(mac hash.Hash) int {
+	marshaler, canMarshal := mac.(encoding.BinaryMarshaler)
+	_, canUnmarshal := mac.(encoding.BinaryUnmarshaler)
+	if !canMarshal || !canUnmarshal {
+		return 0
+	}
+
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return 0
+	}
+
+	return len(state)
+}
+
+// Derive the key schedule of the secret into the schedule buffer,
+// which must be keyScheduleSize bytes long.
+func newKeyedMac(hashFunction func() hash.Hash, secret []byte, schedule []byte) *keyedMac {
+	k := &keyedMac{
+		inner: hashFunction(),
+		outer: hashFunction(),
+	}
+
+	blockSize := k.inner.BlockSize()
+	stateSize := marshaledStateSize(k.inner)
+
+	k.innerPad = schedule[:blockSize]
+	k.outerPad = schedule[blockSize : 2*blockSize]
+	k.digest = make([]byte, 0, k.inner.Size())
+
+	// Keys longer than the block size are hashed first
+	key := secret
+	if len(key) > blockSize {
+		k.outer.Write(key)
+		key = k.outer.Sum(nil)
+		defer Zero(key)
+		k.outer.Reset()
+	}
+
+	copy(k.innerPad, key)
+	copy(k.outerPad, key)
+	for index := range k.innerPad {
+		k.innerPad[index] ^= innerPad
+		k.outerPad[index] ^= outerPad
+	}
+
+	if stateSize == 0 {
+		return k
+	}
+
+	k.innerState = schedule[2*blockSize : 2*blockSize+stateSize]
+	k.outerState = schedule[2*blockSize+stateSize : 2*blockSize+2*stateSize]
+	k.storeState(k.inner, k.innerPad, k.innerState)
+	k.storeState(k.outer, k.outerPad, k.outerState)
+
+	return k
+}
+
+// Hash the padded key and copy the marshaled state into the buffer.
+func (k *keyedMac) storeState(mac hash.Hash, pad []byte, buffer []byte) {
+	mac.Write(pad)
+	state, _ := mac.(encoding.BinaryMarshaler).MarshalBinary()
+	copy(buffer, state)
+	Zero(state)
+	mac.Reset()
+}
+
+// Start the hash with the padded key, from the stored state if possible.
+func (k *keyedMac) restore(mac hash.Hash, pad []byte, state []byte) {
+	if state != nil && mac.(encoding.BinaryUnmarshaler).UnmarshalBinary(state) == nil {
+		return
+	}
+
+	mac.Reset()
+	mac.Write(pad)
+}
+
+// Calculate hmac digest of the moving Factor.
+// The returned slice is only valid until the next call.
+func (k *keyedMac) sum(movingFactor uint64) []byte {
+	binary.BigEndian.PutUint64(k.counter[:], movingFactor)
+
+	k.restore(k.inner, k.innerPad, k.innerState)
+	k.inner.Write(k.counter[:])
+	k.digest = k.inner.Sum(k.digest[:0])
+
+	k.restore(k.outer, k.outerPad, k.outerState)
+	k.outer.Write(k.digest)
+	k.digest = k.outer.Sum(k.digest[:0])
+
+	// The hashes don't keep key dependent state between calls
+	k.inner.Reset()
+	k.outer.Reset()
+
+	return k.digest
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package hotp
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Allocate whole pages outside of the Go heap and lock them into RAM.
+func allocateLocked(size int) ([]byte, error) {
+	pageSize := syscall.Getpagesize()
+	length := (size/pageSize + 1) * pageSize
+
+	memory, err := syscall.Mmap(-1, 0, length,
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLockedMemory, err)
+	}
+
+	err = syscall.Mlock(memory)
+	if err != nil {
+		_ = syscall.Munmap(memory)
+		return nil, fmt.Errorf("%w: %v", ErrLockedMemory, err)
+	}
+
+	return memory, nil
+}
+
+// Zero, unlock and release memory returned by allocateLocked.
+func freeLocked(memory []byte) error {
+	Zero(memory)
+
+	err := syscall.Munlock(memory)
+	if err != nil {
+		return err
+	}
+
+	return syscall.Munmap(memory)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package hotp
+
+// Locked memory is only supported on Linux,
+// so the secret is kept on the heap.
+func allocateLocked(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+func freeLocked(memory []byte) error {
+	Zero(memory)
+	return nil
+}
@@ -0,0 +1,216 @@
+package hotp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrPolicyViolation = errors.New("policy violation")
+
+// The rules of a policy.
+const (
+	RuleAlgorithm = "algorithm"
+	RuleSecret    = "secret"
+	RuleDigits    = "digits"
+	RuleWindow    = "window"
+	RuleResync    = "resync"
+	RuleDrift     = "drift"
+)
+
+// Policy describes the security requirements for keys and their
+// verification. Rules with a zero value are not enforced.
+//
+// Example:
+//
+//	policy := Policy{
+//		ForbiddenAlgorithms: []Algorithm{Sha1},
+//		MinSecretBits:       160,
+//		MinDigits:           8,
+//		MaxWindow:           1,
+//		MaxResyncWindow:     100,
+//		MaxDrift:            10,
+//	}
+type Policy struct {
+	// Algorithms, that must not be used.
+	ForbiddenAlgorithms []Algorithm
+
+	// The min size of the secret in bits.
+	MinSecretBits uint
+
+	// The min amount of digits of a code.
+	MinDigits uint
+
+	// The max amount of counter values or time steps, that are accepted
+	// next to the expected one in each direction.
+	MaxWindow uint
+
+	// The max amount of counter values, that Resync searches.
+	MaxResyncWindow uint
+
+	// The max amount of time steps, that the window of a totp verification
+	// is shifted by its drift in either direction.
+	MaxDrift uint
+}
+
+// Violation of a rule of a policy.
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+func (v Violation) String() string {
+	return v.Rule + ": " + v.Reason
+}
+
+// The parameters of a key, that are checked by a policy.
+type PolicyKey interface {
+	Algorithm() Algorithm
+	Digits() uint
+	SecretSize() uint
+}
+
+// Check the parameters of the key and return every violation.
+func (p Policy) Check(key PolicyKey) []Violation {
+	violations := []Violation{}
+
+	for _, algorithm := range p.ForbiddenAlgorithms {
+		if strings.EqualFold(string(algorithm), string(key.Algorithm())) {
+			violations = append(violations, Violation{
+				Rule:   RuleAlgorithm,
+				Reason: fmt.Sprintf("%s is forbidden", key.Algorithm()),
+			})
+		}
+	}
+
+	secretBits := key.SecretSize() * 8
+	if secretBits < p.MinSecretBits {
+		violations = append(violations, Violation{
+			Rule:   RuleSecret,
+			Reason: fmt.Sprintf("%d bits is less than %d bits", secretBits, p.MinSecretBits),
+		})
+	}
+
+	if key.Digits() < p.MinDigits {
+		violations = append(violations, Violation{
+			Rule:   RuleDigits,
+			Reason: fmt.Sprintf("%d digits is less than %d digits", key.Digits(), p.MinDigits),
+		})
+	}
+
+	return violations
+}
+
+// Check the window of a verification and return every violation.
+// The window is the amount of counter values or time steps, that are
+// accepted next to the expected one in one direction.
+func (p Policy) CheckWindow(window uint) []Violation {
+	if p.MaxWindow == 0 || window <= p.MaxWindow {
+		return []Violation{}
+	}
+
+	return []Violation{{
+		Rule:   RuleWindow,
+		Reason: fmt.Sprintf("%d steps is more than %d steps", window, p.MaxWindow),
+	}}
+}
+
+// Check the bound of a resynchronisation and return every violation.
+func (p Policy) CheckResyncWindow(bound uint) []Violation {
+	if p.MaxResyncWindow == 0 || bound <= p.MaxResyncWindow {
+		return []Violation{}
+	}
+
+	return []Violation{{
+		Rule:   RuleResync,
+		Reason: fmt.Sprintf("%d counter values is more than %d counter values", bound, p.MaxResyncWindow),
+	}}
+}
+
+// Check the drift of a totp verification and return every violation.
+func (p Policy) CheckDrift(drift int64) []Violation {
+	steps := uint64(drift)
+	if drift < 0 {
+		steps = uint64(-drift)
+	}
+
+	if p.MaxDrift == 0 || steps <= uint64(p.MaxDrift) {
+		return []Violation{}
+	}
+
+	return []Violation{{
+		Rule:   RuleDrift,
+		Reason: fmt.Sprintf("%d steps is more than %d steps", drift, p.MaxDrift),
+	}}
+}
+
+// Validate the parameters of the key.
+// The error wraps ErrPolicyViolation and lists every violation.
+func (p Policy) Validate(key PolicyKey) error {
+	return ViolationsToError(p.Check(key))
+}
+
+// Combine the violations into one error, that wraps ErrPolicyViolation.
+// It returns nil, if there are no violations.
+func ViolationsToError(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		reasons = append(reasons, violation.String())
+	}
+
+	return fmt.Errorf("%w: %s", ErrPolicyViolation, strings.Join(reasons, "; "))
+}
+
+// Whether the policy allows the window.
+// A missing policy allows every window.
+func (p *Policy) allowsWindow(window uint) bool {
+	return p == nil || len(p.CheckWindow(window)) == 0
+}
+
+// Limit a default window, that was not chosen by the caller,
+// to the max window of the policy.
+func (p *Policy) limitDefaultWindow(window uint) uint {
+	if p == nil || p.MaxWindow == 0 || window <= p.MaxWindow {
+		return window
+	}
+
+	return p.MaxWindow
+}
+
+// Reject keys, that violate the policy, with an error wrapping
+// ErrPolicyViolation. Verify rejects every code, if its window is larger
+// than the policy allows, and so does Resync for its bound.
+func WithPolicy(policy Policy) HotpOption {
+	return func(ho *hotpOptions) {
+		ho.policy = &policy
+	}
+}
+
+// The policy of the instance, if one is set.
+func (h *Hotp) Policy() (Policy, bool) {
+	if h.policy == nil {
+		return Policy{}, false
+	}
+
+	return *h.policy, true
+}
+
+// The size of the secret in bytes.
+func (h *Hotp) SecretSize() uint {
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	return uint(len(h.secret))
+}
+
+// Reject every code, if the look-ahead window is larger than the policy
+// allows. The policy of the instance is applied as well.
+func WithVerifyPolicy(policy Policy) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.policy = &policy
+	}
+}
@@ -0,0 +1,47 @@
+package hotp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The max amount of codes, that CalculateRange calculates at once.
+const MaxRange = 10000
+
+var ErrRangeTooLarge = errors.New("range is too large")
+
+// CounterCode is a Hotp code together with its counter value.
+type CounterCode struct {
+	Counter uint64
+	Code    Code
+}
+
+// Calculates the codes for the counter values from..from+n-1.
+// The keyed HMAC state is shared across the whole batch.
+// The range ends early, if the counter reaches its maximum value.
+//
+// An error is returned, if n is larger than MaxRange.
+func (h *Hotp) CalculateRange(from uint64, n uint) ([]CounterCode, error) {
+	if n > MaxRange {
+		return nil, fmt.Errorf("%w: %d codes is more than %d codes", ErrRangeTooLarge, n, MaxRange)
+	}
+
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	codes := make([]CounterCode, 0, n)
+
+	for offset := uint64(0); offset < uint64(n); offset++ {
+		counter := from + offset
+		if counter < from {
+			break
+		}
+
+		codes = append(codes, CounterCode{
+			Counter: counter,
+			Code:    h.calculate(counter),
+		})
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,106 @@
+package hotp
+
+import (
+	"errors"
+	"hash"
+)
+
+var (
+	ErrLockedMemory = errors.New("can't lock the memory of the secret")
+	ErrDestroyed    = errors.New("the instance is destroyed")
+)
+
+// Keep the secret in memory, that is locked into RAM, so it is never
+// written to swap. This is only supported on Linux and has no effect
+// on other platforms.
+//
+// The key schedule of HMAC, derived from the secret, is locked as well.
+// The hash function keeps intermediate states on the heap only while a
+// digest is calculated.
+func WithLockedMemory() HotpOption {
+	return func(ho *hotpOptions) {
+		ho.lockedMemory = true
+	}
+}
+
+// Copy the secret and derive the key schedule of HMAC into memory owned
+// by the Hotp instance, so both can be zeroed by Destroy without touching
+// the callers slice.
+func (h *Hotp) storeSecret(secret []byte, hashFunction func() hash.Hash, lockedMemory bool) error {
+	size := len(secret) + keyScheduleSize(hashFunction)
+
+	var memory []byte
+	if lockedMemory {
+		var err error
+		memory, err = allocateLocked(size)
+		if err != nil {
+			return err
+		}
+
+		h.memory = memory
+	} else {
+		memory = make([]byte, size)
+	}
+
+	h.secret = memory[:len(secret)]
+	copy(h.secret, secret)
+	h.mac = newKeyedMac(hashFunction, h.secret, memory[len(secret):size])
+
+	return nil
+}
+
+// Destroy zeroes the secret and the key schedule of HMAC.
+// Locked memory is unlocked and released.
+//
+// Afterwards, Calculate returns the zero Code and every verification fails.
+func (h *Hotp) Destroy() {
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	Zero(h.secret)
+	h.secret = nil
+	h.mac.destroy()
+
+	if h.memory != nil {
+		_ = freeLocked(h.memory)
+		h.memory = nil
+	}
+}
+
+// Whether Destroy was called on the instance.
+func (h *Hotp) IsDestroyed() bool {
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	return h.mac.destroyed
+}
+
+// Close implements io.Closer by calling Destroy.
+func (h *Hotp) Close() error {
+	h.Destroy()
+	return nil
+}
+
+// Zero the key schedule and the last digest
+// and mark the state as destroyed, so it is never used again.
+func (k *keyedMac) destroy() {
+	k.destroyed = true
+
+	for _, buffer := range [][]byte{k.innerPad, k.outerPad, k.innerState, k.outerState} {
+		Zero(buffer)
+	}
+
+	k.innerPad, k.outerPad, k.innerState, k.outerState = nil, nil, nil, nil
+	k.inner.Reset()
+	k.outer.Reset()
+	Zero(k.digest[:cap(k.digest)])
+	Zero(k.counter[:])
+}
+
+// Zero overwrites the buffer with zeros,
+// like a copy of the secret, once it is no longer needed.
+func Zero(buffer []byte) {
+	for index := range buffer {
+		buffer[index] = 0
+	}
+}
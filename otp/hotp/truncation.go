@@ -0,0 +1,126 @@
+package hotp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Alphabets for the characters of a code.
+// The position of a character is its value.
+const (
+	Decimal      = "0123456789"
+	Hexadecimal  = "0123456789ABCDEF"
+	Alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+var (
+	ErrInvalidOffset   = errors.New("invalid truncation offset")
+	ErrInvalidAlphabet = errors.New("invalid alphabet")
+)
+
+// Extract the 4 bytes of the code at a fixed offset of the HMAC digest,
+// instead of the dynamic truncation of RFC 4226 section 5.3.
+//
+// Keep in mind that the max value of the offset is the size of the
+// digest minus four bytes. Therefore, the offset has to be between
+// (inclusive) 0 and 16 for SHA1 (20 byte digest), 28 for SHA256
+// (32 byte digest) and 60 for SHA512 (64 byte digest).
+func WithTruncationOffset(offset uint8) HotpOption {
+	return func(ho *hotpOptions) {
+		ho.offset = &offset
+	}
+}
+
+// Encode the code with the characters of the alphabet instead of
+// decimal digits. The alphabet needs at least two distinct ASCII
+// characters.
+//
+// Example:
+//
+//	hotp, err := New(secret, WithAlphabet(Hexadecimal))
+func WithAlphabet(alphabet string) HotpOption {
+	return func(ho *hotpOptions) {
+		ho.alphabet = alphabet
+	}
+}
+
+// The fixed truncation offset, if one is set.
+func (h *Hotp) TruncationOffset() (uint8, bool) {
+	if h.offset == nil {
+		return 0, false
+	}
+
+	return *h.offset, true
+}
+
+// The alphabet of the codes.
+func (h *Hotp) Alphabet() string {
+	if h.alphabet == "" {
+		return Decimal
+	}
+
+	return h.alphabet
+}
+
+// Parse a code entered by a user, using the digits and the alphabet
+// of the instance.
+func (h *Hotp) ParseCode(input string) (Code, error) {
+	return ParseCodeWithAlphabet(input, h.digits, h.Alphabet())
+}
+
+// The offset has to leave room for 4 bytes in the digest
+func validateOffset(offset *uint8, digestSize int) error {
+	if offset == nil {
+		return nil
+	}
+
+	if int(*offset) > digestSize-4 {
+		return fmt.Errorf("%w: %d is larger than %d", ErrInvalidOffset, *offset, digestSize-4)
+	}
+
+	return nil
+}
+
+// The alphabet has to consist of distinct ASCII characters.
+// The decimal alphabet is normalized to an empty string.
+func normalizeAlphabet(alphabet string) (string, error) {
+	if alphabet == "" || alphabet == Decimal {
+		return "", nil
+	}
+
+	if len(alphabet) < 2 {
+		return "", fmt.Errorf("%w: %q needs at least two characters", ErrInvalidAlphabet, alphabet)
+	}
+
+	seen := map[rune]bool{}
+	for _, character := range alphabet {
+		if character > 127 {
+			return "", fmt.Errorf("%w: %q contains non ASCII characters", ErrInvalidAlphabet, alphabet)
+		}
+
+		if seen[character] {
+			return "", fmt.Errorf("%w: %q contains %q twice", ErrInvalidAlphabet, alphabet, character)
+		}
+
+		seen[character] = true
+	}
+
+	return alphabet, nil
+}
+
+// The modulus to shorten the code to the digits of the alphabet.
+// It is 0, if the code fits without shortening.
+func calculateModulus(digits uint, base int) uint32 {
+	modulus := uint64(1)
+
+	for digit := uint(0); digit < digits; digit++ {
+		modulus *= uint64(base)
+
+		// The code has 31 bit, so it never needs to be shortened
+		if modulus > 0x7fffffff {
+			return 0
+		}
+	}
+
+	return uint32(modulus)
+}
@@ -0,0 +1,154 @@
+package hotp
+
+type verifyOptions struct {
+	lookAhead      uint
+	isLookAheadSet bool
+	policy         *Policy
+}
+
+type VerifyOption func(*verifyOptions)
+
+// Accept codes for up to lookAhead counter values after the stored counter.
+func WithLookAhead(lookAhead uint) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.lookAhead = lookAhead
+		vo.isLookAheadSet = true
+	}
+}
+
+const defaultLookAhead uint = 10
+
+// Apply the options on top of the defaults.
+// Verifying without options does not allocate.
+func newVerifyOptions(options []VerifyOption) verifyOptions {
+	if len(options) == 0 {
+		return verifyOptions{
+			lookAhead: defaultLookAhead,
+		}
+	}
+
+	opts := &verifyOptions{
+		lookAhead: defaultLookAhead,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	return *opts
+}
+
+// Verify checks a code against the counter values
+// counter..counter+lookAhead as described in RFC 4226 section 7.2.
+// Counter values behind the stored counter are never accepted.
+//
+// On success the counter, that has to be stored for the next verification,
+// is returned. It is the matched counter value plus one.
+//
+// If the look-ahead window of WithLookAhead is larger than the policy of
+// the instance or of WithVerifyPolicy allows, every code is rejected.
+// Use CheckWindow of the policy to find the violation. Without
+// WithLookAhead, the default window is limited to the policies instead.
+//
+// Example:
+//
+//	counter, ok := hotp.Verify(code, storedCounter, WithLookAhead(5))
+//	if ok {
+//		storedCounter = counter
+//	}
+func (h *Hotp) Verify(code Code, counter uint64, options ...VerifyOption) (uint64, bool) {
+	opts := newVerifyOptions(options)
+
+	if !opts.isLookAheadSet {
+		opts.lookAhead = h.policy.limitDefaultWindow(opts.lookAhead)
+		opts.lookAhead = opts.policy.limitDefaultWindow(opts.lookAhead)
+	}
+
+	if !h.policy.allowsWindow(opts.lookAhead) || !opts.policy.allowsWindow(opts.lookAhead) {
+		return counter, false
+	}
+
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	// The keyed state of a destroyed instance uses an empty key
+	if h.mac.destroyed {
+		return counter, false
+	}
+
+	for offset := uint64(0); offset <= uint64(opts.lookAhead); offset++ {
+		movingFactor := counter + offset
+
+		// The counter can not move past its maximum value
+		if movingFactor < counter || movingFactor == ^uint64(0) {
+			break
+		}
+
+		if h.calculate(movingFactor).Equal(code) {
+			return movingFactor + 1, true
+		}
+	}
+
+	return counter, false
+}
+
+// Resync searches the counter values counter..counter+bound for two
+// consecutive codes as described in RFC 4226 section 7.4.
+// It is meant for tokens, that drifted past the look-ahead window of Verify,
+// so bound is usually a lot larger than the look-ahead window.
+//
+// On success the counter, that has to be stored for the next verification,
+// is returned. It is the counter value of the second code plus one.
+//
+// If the bound is larger than the policy of the instance allows, the codes
+// are rejected. Use CheckResyncWindow of the policy to find the violation.
+func (h *Hotp) Resync(first Code, second Code, counter uint64, bound uint) (uint64, bool) {
+	if h.policy != nil && len(h.policy.CheckResyncWindow(bound)) > 0 {
+		return counter, false
+	}
+
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	if h.mac.destroyed {
+		return counter, false
+	}
+
+	for offset := uint64(0); offset <= uint64(bound); offset++ {
+		movingFactor := counter + offset
+
+		// Both codes and the following counter have to fit into the counter
+		if movingFactor < counter || movingFactor >= ^uint64(0)-1 {
+			break
+		}
+
+		if !h.calculate(movingFactor).Equal(first) {
+			continue
+		}
+
+		if h.calculate(movingFactor + 1).Equal(second) {
+			return movingFactor + 2, true
+		}
+	}
+
+	return counter, false
+}
+
+// Check reports whether the code matches the counter exactly.
+// Use Verify to accept codes of a look-ahead window.
+func (h *Hotp) Check(code Code, counter uint64) bool {
+	h.mac.Lock()
+	defer h.mac.Unlock()
+
+	if h.mac.destroyed {
+		return false
+	}
+
+	return h.calculate(counter).Equal(code)
+}
+
+// VerifyAt verifies the code like Verify with the default look-ahead window.
+// It implements the Verifier interface of the otp package.
+func (h *Hotp) VerifyAt(code Code, counter uint64) (uint64, bool) {
+	return h.Verify(code, counter)
+}
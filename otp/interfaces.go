@@ -0,0 +1,74 @@
+package otp
+
+import (
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/totp"
+)
+
+type Code = hotp.Code
+
+type Algorithm = hotp.Algorithm
+
+type Fingerprint = hotp.Fingerprint
+
+// Policy describes the security requirements for keys and their
+// verification. See hotp.Policy for details.
+type Policy = hotp.Policy
+
+type Violation = hotp.Violation
+
+// Metadata describes a token independent of its type.
+type Metadata interface {
+	Account() string
+	Issuer() string
+	Label() string
+	Digits() uint
+	Algorithm() Algorithm
+	Fingerprint(key []byte) (Fingerprint, error)
+}
+
+// Generator calculates the codes of a token.
+//
+// The moving factor is the counter for hotp tokens
+// and the unix time in seconds for totp tokens.
+type Generator interface {
+	Metadata
+	Calculate(movingFactor uint64) Code
+}
+
+// Verifier verifies codes of a token, like a server does.
+//
+// The moving factor is the stored counter for hotp tokens and the
+// current unix time in seconds for totp tokens. The code is checked
+// against the default window of the token, which is the look-ahead
+// window for hotp and one step in each direction for totp.
+//
+// On success the moving factor to store is returned. It is the counter
+// after the matched one for hotp and the unix time, at which the step
+// after the matched one starts, for totp. Reject a code as replayed,
+// if the returned moving factor is not greater than the stored one.
+//
+// Example:
+//
+//	next, ok := verifier.VerifyAt(code, movingFactor)
+//	if !ok || next <= user.Stored {
+//		return ErrInvalidCode
+//	}
+//	user.Stored = next
+type Verifier interface {
+	Metadata
+	VerifyAt(code Code, movingFactor uint64) (uint64, bool)
+}
+
+var (
+	_ Generator = (*hotp.Hotp)(nil)
+	_ Verifier  = (*hotp.Hotp)(nil)
+	_ Generator = (*totp.Totp)(nil)
+	_ Verifier  = (*totp.Totp)(nil)
+	_ Generator = (*Key)(nil)
+	_ Verifier  = (*Key)(nil)
+
+	_ hotp.PolicyKey = (*hotp.Hotp)(nil)
+	_ hotp.PolicyKey = (*totp.Totp)(nil)
+	_ hotp.PolicyKey = (*Key)(nil)
+)
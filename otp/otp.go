@@ -0,0 +1,302 @@
+// Package otp parses otpauth Key URIs of any supported type.
+//
+// Use the hotp and totp packages directly, if the type of the token is
+// known in advance.
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/totp"
+	"bode.fun/otp/uri"
+)
+
+type Type string
+
+const (
+	TypeHotp Type = uri.TypeHotp
+	TypeTotp Type = uri.TypeTotp
+)
+
+var ErrUnsupportedType = errors.New("unsupported otp type")
+
+type parseOptions struct {
+	lockedMemory bool
+	policy       *Policy
+}
+
+type ParseOption func(*parseOptions)
+
+// Keep the secret in memory, that is locked into RAM.
+// See hotp.WithLockedMemory for details.
+func WithLockedMemory() ParseOption {
+	return func(po *parseOptions) {
+		po.lockedMemory = true
+	}
+}
+
+// Reject tokens, that violate the policy, with an error wrapping
+// hotp.ErrPolicyViolation. See hotp.Policy for details.
+func WithPolicy(policy Policy) ParseOption {
+	return func(po *parseOptions) {
+		po.policy = &policy
+	}
+}
+
+// Key is a parsed otp token of any supported type.
+type Key struct {
+	hotp    *hotp.Hotp
+	totp    *totp.Totp
+	counter uint64
+}
+
+// Parse an otpauth Key URI and create the matching token.
+// Legacy URIs, with the issuer after the account, are accepted as well.
+//
+// Example:
+//
+//	key, err := Parse("otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+//	if err != nil {
+//		return err
+//	}
+//	defer key.Destroy()
+//
+//	fmt.Println(key.Code(time.Now()))
+func Parse(rawUri string, options ...ParseOption) (*Key, error) {
+	opts := &parseOptions{}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	key, err := uri.ParseLenient(rawUri)
+	if err != nil {
+		return nil, err
+	}
+	defer hotp.Zero(key.Secret)
+
+	switch Type(key.Type) {
+	case TypeHotp:
+		hotpOptions := []hotp.HotpOption{}
+		if opts.lockedMemory {
+			hotpOptions = append(hotpOptions, hotp.WithLockedMemory())
+		}
+
+		if opts.policy != nil {
+			hotpOptions = append(hotpOptions, hotp.WithPolicy(*opts.policy))
+		}
+
+		hotpInstance, counter, err := hotp.NewFromKey(key, hotpOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Key{
+			hotp:    hotpInstance,
+			counter: counter,
+		}, nil
+	case TypeTotp:
+		totpOptions := []totp.TotpOption{}
+		if opts.lockedMemory {
+			totpOptions = append(totpOptions, totp.WithLockedMemory())
+		}
+
+		if opts.policy != nil {
+			totpOptions = append(totpOptions, totp.WithPolicy(*opts.policy))
+		}
+
+		totpInstance, err := totp.NewFromKey(key, totpOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Key{
+			totp: totpInstance,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedType, key.Type)
+	}
+}
+
+func (k *Key) Type() Type {
+	if k.hotp != nil {
+		return TypeHotp
+	}
+
+	return TypeTotp
+}
+
+// The Hotp instance, if the key is a hotp token.
+func (k *Key) Hotp() (*hotp.Hotp, bool) {
+	return k.hotp, k.hotp != nil
+}
+
+// The Totp instance, if the key is a totp token.
+func (k *Key) Totp() (*totp.Totp, bool) {
+	return k.totp, k.totp != nil
+}
+
+// The counter of a hotp token. It is always 0 for totp tokens.
+func (k *Key) Counter() uint64 {
+	return k.counter
+}
+
+func (k *Key) Digits() uint {
+	if k.hotp != nil {
+		return k.hotp.Digits()
+	}
+
+	return k.totp.Digits()
+}
+
+func (k *Key) Algorithm() hotp.Algorithm {
+	if k.hotp != nil {
+		return k.hotp.Algorithm()
+	}
+
+	return k.totp.Algorithm()
+}
+
+// A copy of the secret of the token.
+// The caller is responsible for zeroing it after use.
+func (k *Key) Secret() []byte {
+	if k.hotp != nil {
+		return k.hotp.Secret()
+	}
+
+	return k.totp.Secret()
+}
+
+// The size of the secret in bytes.
+func (k *Key) SecretSize() uint {
+	if k.hotp != nil {
+		return k.hotp.SecretSize()
+	}
+
+	return k.totp.SecretSize()
+}
+
+func (k *Key) Account() string {
+	if k.hotp != nil {
+		return k.hotp.Account()
+	}
+
+	return k.totp.Account()
+}
+
+func (k *Key) Issuer() string {
+	if k.hotp != nil {
+		return k.hotp.Issuer()
+	}
+
+	return k.totp.Issuer()
+}
+
+func (k *Key) Label() string {
+	if k.hotp != nil {
+		return k.hotp.Label()
+	}
+
+	return k.totp.Label()
+}
+
+// The fingerprint of the secret and the parameters of the token,
+// keyed with the key. See hotp.NewFingerprint for details.
+func (k *Key) Fingerprint(key []byte) (hotp.Fingerprint, error) {
+	if k.hotp != nil {
+		return k.hotp.Fingerprint(key)
+	}
+
+	return k.totp.Fingerprint(key)
+}
+
+// The current code of the token.
+// Hotp tokens use their counter and ignore the timestamp.
+// The counter is not advanced, so hotp tokens return the same code
+// every time. Use NextCode to consume the code.
+func (k *Key) Code(timestamp time.Time) hotp.Code {
+	if k.hotp != nil {
+		return k.hotp.Calculate(k.counter)
+	}
+
+	return k.totp.At(timestamp)
+}
+
+// NextCode returns the current code like Code and advances the counter
+// of hotp tokens, so the following call returns the next code.
+// Store ToUrl afterwards to persist the counter.
+func (k *Key) NextCode(timestamp time.Time) hotp.Code {
+	code := k.Code(timestamp)
+
+	// The counter can not move past its maximum value
+	if k.hotp != nil && k.counter < ^uint64(0) {
+		k.counter++
+	}
+
+	return code
+}
+
+// Calculates the code of the token.
+// The moving factor is the counter for hotp tokens
+// and the unix time in seconds for totp tokens.
+func (k *Key) Calculate(movingFactor uint64) hotp.Code {
+	if k.hotp != nil {
+		return k.hotp.Calculate(movingFactor)
+	}
+
+	return k.totp.Calculate(movingFactor)
+}
+
+// VerifyAt verifies the code with the default window of the token.
+// See Verifier for details.
+func (k *Key) VerifyAt(code hotp.Code, movingFactor uint64) (uint64, bool) {
+	if k.hotp != nil {
+		return k.hotp.VerifyAt(code, movingFactor)
+	}
+
+	return k.totp.VerifyAt(code, movingFactor)
+}
+
+// Format the key as otpauth Key URI.
+func (k *Key) ToUrl() string {
+	if k.hotp != nil {
+		return k.hotp.ToUrl(k.counter)
+	}
+
+	return k.totp.ToUrl()
+}
+
+// The text form of a key is its otpauth url, which includes the counter
+// of hotp tokens. A destroyed key returns hotp.ErrDestroyed.
+func (k *Key) MarshalText() ([]byte, error) {
+	if k.hotp != nil && k.hotp.IsDestroyed() || k.totp != nil && k.totp.IsDestroyed() {
+		return nil, hotp.ErrDestroyed
+	}
+
+	return []byte(k.ToUrl()), nil
+}
+
+// Parse an otpauth url into the key, see Parse.
+func (k *Key) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*k = *parsed
+	return nil
+}
+
+// Destroy zeroes the secret of the token.
+// See hotp.Hotp.Destroy for details.
+func (k *Key) Destroy() {
+	if k.hotp != nil {
+		k.hotp.Destroy()
+		return
+	}
+
+	k.totp.Destroy()
+}
@@ -0,0 +1,158 @@
+package otp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"bode.fun/otp"
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/totp"
+	"bode.fun/otp/uri"
+	"github.com/matryer/is"
+)
+
+func Test_Parse(t *testing.T) {
+	is := is.New(t)
+
+	{
+		key, err := otp.Parse("otpauth://hotp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=5&issuer=Example")
+		is.NoErr(err)
+		defer key.Destroy()
+
+		is.Equal(otp.TypeHotp, key.Type())
+		is.Equal(uint64(5), key.Counter())
+		is.Equal("Example", key.Issuer())
+		is.Equal("alice@example.com", key.Account())
+		is.Equal("254676", key.Code(time.Now()).String())
+		is.Equal("254676", key.Code(time.Now()).String())
+
+		// The counter only advances with NextCode
+		is.Equal("254676", key.NextCode(time.Now()).String())
+		is.Equal("287922", key.NextCode(time.Now()).String())
+		is.Equal(uint64(7), key.Counter())
+
+		parsed, err := otp.Parse(key.ToUrl())
+		is.NoErr(err)
+		is.Equal(uint64(7), parsed.Counter())
+
+		_, isTotp := key.Totp()
+		is.True(!isTotp)
+	}
+
+	{
+		key, err := otp.Parse(
+			"otpauth://totp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=8",
+			otp.WithLockedMemory(),
+		)
+		is.NoErr(err)
+		defer key.Destroy()
+
+		is.Equal(otp.TypeTotp, key.Type())
+		is.Equal(uint(8), key.Digits())
+		is.Equal("94287082", key.Code(time.Unix(59, 0)).String())
+
+		totpInstance, isTotp := key.Totp()
+		is.True(isTotp)
+		is.Equal(uint(30), totpInstance.Period())
+	}
+
+	{
+		_, err := otp.Parse("otpauth://motp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+		is.True(errors.Is(err, uri.ErrInvalidType))
+	}
+
+	{
+		_, err := otp.Parse(
+			"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			otp.WithPolicy(otp.Policy{MinDigits: 8}),
+		)
+		is.True(errors.Is(err, hotp.ErrPolicyViolation))
+	}
+}
+
+func Test_Encoding(t *testing.T) {
+	is := is.New(t)
+
+	key, err := otp.Parse("otpauth://hotp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=5&issuer=Example")
+	is.NoErr(err)
+	key.NextCode(time.Now())
+
+	// The counter is part of the text and JSON form of a key
+	data, err := json.Marshal(struct {
+		Key *otp.Key `json:"key"`
+	}{key})
+	is.NoErr(err)
+
+	var config struct {
+		Key *otp.Key `json:"key"`
+	}
+	is.NoErr(json.Unmarshal(data, &config))
+	defer config.Key.Destroy()
+
+	is.Equal(otp.TypeHotp, config.Key.Type())
+	is.Equal(uint64(6), config.Key.Counter())
+	is.Equal(key.Code(time.Now()), config.Key.Code(time.Now()))
+
+	key.Destroy()
+	_, err = key.MarshalText()
+	is.True(errors.Is(err, hotp.ErrDestroyed))
+}
+
+func Test_Interfaces(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	hotpInstance, err := hotp.New(secret, hotp.WithDigits(8))
+	is.NoErr(err)
+
+	totpInstance, err := totp.New(secret, totp.WithDigits(8))
+	is.NoErr(err)
+
+	key, err := otp.Parse(totpInstance.ToUrl())
+	is.NoErr(err)
+
+	// The second time step of totp uses the counter 1 of hotp
+	generators := []otp.Generator{hotpInstance, totpInstance, key}
+	movingFactors := []uint64{1, 59, 59}
+
+	for index, generator := range generators {
+		is.Equal("94287082", generator.Calculate(movingFactors[index]).String())
+		is.Equal(uint(8), generator.Digits())
+		is.Equal(hotp.Sha1, generator.Algorithm())
+	}
+
+	code, err := hotp.ParseCode("94287082", 8)
+	is.NoErr(err)
+
+	// Hotp stores the next counter and totp the start of the next step
+	verifiers := []otp.Verifier{hotpInstance, totpInstance, key}
+	nextMovingFactors := []uint64{2, 60, 60}
+
+	for index, verifier := range verifiers {
+		next, isValid := verifier.VerifyAt(code, movingFactors[index])
+		is.True(isValid)
+		is.Equal(nextMovingFactors[index], next)
+	}
+
+	{
+		// Hotp looks ahead of the stored counter
+		next, isValid := hotpInstance.VerifyAt(code, 0)
+		is.True(isValid)
+		is.Equal(uint64(2), next)
+
+		_, isValid = hotpInstance.VerifyAt(code, 2)
+		is.True(!isValid)
+	}
+
+	{
+		// Totp accepts one step in each direction
+		next, isValid := totpInstance.VerifyAt(code, 89)
+		is.True(isValid)
+		is.Equal(uint64(60), next)
+
+		_, isValid = totpInstance.VerifyAt(code, 120)
+		is.True(!isValid)
+	}
+}
@@ -0,0 +1,26 @@
+package totp
+
+import "time"
+
+// Clock provides the current time to a Totp instance.
+//
+// It can be replaced to use a NTP corrected time source
+// or a fixed time in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to use a function as Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var defaultClock Clock = systemClock{}
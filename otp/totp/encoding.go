@@ -0,0 +1,134 @@
+package totp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/uri"
+)
+
+// The text form of a Totp instance is its otpauth url.
+// A destroyed instance returns ErrDestroyed.
+func (t *Totp) MarshalText() ([]byte, error) {
+	if t.hotp.IsDestroyed() {
+		return nil, ErrDestroyed
+	}
+
+	return []byte(t.ToUrl()), nil
+}
+
+// Parse an otpauth url into the instance.
+func (t *Totp) UnmarshalText(text []byte) error {
+	parsed, err := NewFromUrl(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = *parsed
+	return nil
+}
+
+// The JSON form of a Totp instance.
+//
+// Example:
+//
+//	{
+//		"type": "totp",
+//		"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+//		"algorithm": "sha1",
+//		"digits": 6,
+//		"period": 30,
+//		"t0": 0,
+//		"account": "alice@example.com",
+//		"issuer": "Example",
+//		"offset": 4,
+//		"alphabet": "0123456789ABCDEF",
+//		"extensions": {"image": ["https://example.com/logo.png"]}
+//	}
+//
+// The secret is base32 encoded without padding and t0 is the epoch in unix
+// seconds. T0, account, issuer, offset, alphabet and extensions are omitted,
+// if they are empty.
+type jsonTotp struct {
+	Type       string     `json:"type"`
+	Secret     string     `json:"secret"`
+	Algorithm  Algorithm  `json:"algorithm"`
+	Digits     uint       `json:"digits"`
+	Period     uint       `json:"period"`
+	T0         int64      `json:"t0,omitempty"`
+	Account    string     `json:"account,omitempty"`
+	Issuer     string     `json:"issuer,omitempty"`
+	Offset     *uint8     `json:"offset,omitempty"`
+	Alphabet   string     `json:"alphabet,omitempty"`
+	Extensions url.Values `json:"extensions,omitempty"`
+}
+
+const jsonType = "totp"
+
+// A destroyed instance returns ErrDestroyed.
+func (t *Totp) MarshalJSON() ([]byte, error) {
+	if t.hotp.IsDestroyed() {
+		return nil, ErrDestroyed
+	}
+
+	secret := t.Secret()
+	defer hotp.Zero(secret)
+
+	var offset *uint8
+	if truncationOffset, hasOffset := t.TruncationOffset(); hasOffset {
+		offset = &truncationOffset
+	}
+
+	return json.Marshal(jsonTotp{
+		Type:       jsonType,
+		Secret:     uri.SecretEncoding.EncodeToString(secret),
+		Algorithm:  t.Algorithm(),
+		Digits:     t.Digits(),
+		Period:     t.StepSize(),
+		T0:         t.epoch,
+		Account:    t.Account(),
+		Issuer:     t.Issuer(),
+		Offset:     offset,
+		Alphabet:   t.alphabet(),
+		Extensions: t.Extensions(),
+	})
+}
+
+func (t *Totp) UnmarshalJSON(data []byte) error {
+	var key jsonTotp
+
+	err := json.Unmarshal(data, &key)
+	if err != nil {
+		return err
+	}
+
+	if key.Type != jsonType {
+		return fmt.Errorf("can't read a %q key as %q", key.Type, jsonType)
+	}
+
+	totpOptions := []TotpOption{
+		WithAlgorithm(key.Algorithm),
+		WithDigits(key.Digits),
+		WithStepSize(key.Period),
+		WithEpoch(time.Unix(key.T0, 0)),
+		WithAccount(key.Account),
+		WithIssuer(key.Issuer),
+		WithAlphabet(key.Alphabet),
+		WithExtensions(key.Extensions),
+	}
+
+	if key.Offset != nil {
+		totpOptions = append(totpOptions, WithTruncationOffset(*key.Offset))
+	}
+
+	parsed, err := NewFromBase32(key.Secret, totpOptions...)
+	if err != nil {
+		return err
+	}
+
+	*t = *parsed
+	return nil
+}
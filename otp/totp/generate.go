@@ -0,0 +1,41 @@
+package totp
+
+import (
+	"bode.fun/otp/hotp"
+	"bode.fun/otp/uri"
+)
+
+// Create a Totp instance with a random secret.
+// The size of the secret is the recommended size for the algorithm.
+// Next to the instance, the unpadded base32 encoded secret is returned.
+//
+// Example:
+//
+//	totp, encodedSecret, err := Generate(
+//				WithAlgorithm(Sha256),
+//				WithDigits(8),
+//			)
+func Generate(options ...TotpOption) (*Totp, string, error) {
+	opts, err := newTotpOptions(options)
+	if err != nil {
+		return nil, "", err
+	}
+
+	algorithm, err := ParseAlgorithm(string(opts.algorithm))
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := hotp.GenerateSecret(algorithm.SecretSize())
+	if err != nil {
+		return nil, "", err
+	}
+	defer hotp.Zero(secret)
+
+	totp, err := New(secret, options...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return totp, uri.SecretEncoding.EncodeToString(secret), nil
+}
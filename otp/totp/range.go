@@ -0,0 +1,64 @@
+package totp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bode.fun/otp/hotp"
+)
+
+// The max amount of codes, that CodesBetween calculates at once.
+const MaxCodesBetween = hotp.MaxRange
+
+var ErrTooManySteps = errors.New("too many time steps")
+
+// Calculates the codes of every time step between start and end,
+// including the steps both of them fall into.
+// An empty slice is returned, if end is before start.
+//
+// An error is returned, if the range spans more than MaxCodesBetween
+// time steps.
+//
+// Example:
+//
+//	timedCodes, err := totp.CodesBetween(start, end)
+//	if err != nil {
+//		return err
+//	}
+//
+//	for _, timedCode := range timedCodes {
+//		fmt.Println(timedCode.Start, timedCode.Code)
+//	}
+func (t *Totp) CodesBetween(start time.Time, end time.Time) ([]TimedCode, error) {
+	if end.Before(start) {
+		return []TimedCode{}, nil
+	}
+
+	firstStep := t.step(start)
+	lastStep := t.step(end)
+
+	// Compare the distance, as the amount of steps can overflow
+	if lastStep-firstStep >= MaxCodesBetween {
+		return nil, fmt.Errorf("%w: the range spans more than %d steps", ErrTooManySteps, MaxCodesBetween)
+	}
+
+	counterCodes, err := t.hotp.CalculateRange(firstStep, uint(lastStep-firstStep+1))
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]TimedCode, 0, len(counterCodes))
+
+	for _, counterCode := range counterCodes {
+		start := t.stepStart(counterCode.Counter)
+
+		codes = append(codes, TimedCode{
+			Code:  counterCode.Code,
+			Step:  counterCode.Counter,
+			Start: start,
+			End:   start.Add(time.Duration(t.stepSize) * time.Second),
+		})
+	}
+
+	return codes, nil
+}
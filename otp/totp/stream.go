@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"context"
+	"time"
+)
+
+// TimedCode is a Totp code together with its time step
+// and the interval, in which it is valid.
+type TimedCode struct {
+	Code  Code
+	Step  uint64
+	Start time.Time
+	End   time.Time
+}
+
+// Stream emits the code of the current time step right away and then
+// again every time the step rolls over. The current time is taken from
+// the clock of the Totp instance.
+//
+// The channel is closed, after the context is cancelled.
+//
+// Example:
+//
+//	for timedCode := range totp.Stream(ctx) {
+//		fmt.Println(timedCode.Code, "valid until", timedCode.End)
+//	}
+func (t *Totp) Stream(ctx context.Context) <-chan TimedCode {
+	codes := make(chan TimedCode)
+
+	go func() {
+		defer close(codes)
+
+		var lastStep uint64
+		hasEmitted := false
+
+		for {
+			now := t.clock.Now()
+			step := t.step(now)
+
+			// The timer can fire before the clock reached the next step
+			if !hasEmitted || step > lastStep {
+				select {
+				case codes <- t.timedCode(step):
+				case <-ctx.Done():
+					return
+				}
+
+				lastStep = step
+				hasEmitted = true
+			}
+
+			timer := time.NewTimer(t.StepEnd(now).Sub(t.clock.Now()))
+
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return codes
+}
+
+func (t *Totp) timedCode(step uint64) TimedCode {
+	start := t.stepStart(step)
+
+	return TimedCode{
+		Code:  t.hotp.Calculate(step),
+		Step:  step,
+		Start: start,
+		End:   start.Add(time.Duration(t.stepSize) * time.Second),
+	}
+}
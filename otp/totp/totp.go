@@ -1,24 +1,81 @@
-// TODO: Add Secret validation and otp verification
-// TODO: Add secret generation
-// TODO: Add otp verification
-// TODO: Add remaining time calculation
-// TODO: Add window for validation
 package totp
 
 import (
-	"encoding/base32"
+	"errors"
 	"fmt"
-	"math"
 	"net/url"
-	"strconv"
-	"strings"
 	"time"
 
 	"bode.fun/otp/hotp"
+	"bode.fun/otp/uri"
 )
 
 type Algorithm = hotp.Algorithm
 
+type Code = hotp.Code
+
+type Fingerprint = hotp.Fingerprint
+
+var ErrFingerprintKeyTooShort = hotp.ErrFingerprintKeyTooShort
+
+// Policy describes the security requirements for keys and their
+// verification. See hotp.Policy for details.
+type Policy = hotp.Policy
+
+type Violation = hotp.Violation
+
+// Alphabets for the characters of a code.
+const (
+	Decimal      = hotp.Decimal
+	Hexadecimal  = hotp.Hexadecimal
+	Alphanumeric = hotp.Alphanumeric
+)
+
+// Encoding of a secret, that is entered by a user.
+type Encoding = hotp.Encoding
+
+const (
+	EncodingAuto   = hotp.EncodingAuto
+	EncodingBase32 = hotp.EncodingBase32
+	EncodingHex    = hotp.EncodingHex
+	EncodingBase64 = hotp.EncodingBase64
+)
+
+var ErrInvalidCode = hotp.ErrInvalidCode
+
+// Parse a code entered by a user.
+// Spaces and dashes, used to group the digits, are ignored.
+func ParseCode(input string, digits uint) (Code, error) {
+	return hotp.ParseCode(input, digits)
+}
+
+var (
+	ErrInvalidAlgorithm = hotp.ErrInvalidAlgorithm
+	ErrInvalidDigits    = hotp.ErrInvalidDigits
+	ErrSecretTooShort   = hotp.ErrSecretTooShort
+	ErrInvalidOffset    = hotp.ErrInvalidOffset
+	ErrInvalidAlphabet  = hotp.ErrInvalidAlphabet
+	ErrInvalidSecret    = hotp.ErrInvalidSecret
+	ErrInvalidEncoding  = hotp.ErrInvalidEncoding
+	ErrPolicyViolation  = hotp.ErrPolicyViolation
+	ErrLockedMemory     = hotp.ErrLockedMemory
+	ErrDestroyed        = hotp.ErrDestroyed
+	ErrInvalidStepSize  = errors.New("invalid step size")
+)
+
+var ErrAmbiguousEncoding = hotp.ErrAmbiguousEncoding
+
+// Parse the name of an encoding case-insensitively.
+func ParseEncoding(name string) (Encoding, error) {
+	return hotp.ParseEncoding(name)
+}
+
+// Parse the name of an algorithm case-insensitively.
+// Authenticator apps usually use the uppercase form, like "SHA1".
+func ParseAlgorithm(name string) (Algorithm, error) {
+	return hotp.ParseAlgorithm(name)
+}
+
 const (
 	Sha1   Algorithm = hotp.Sha1
 	Sha256 Algorithm = hotp.Sha256
@@ -26,11 +83,18 @@ const (
 )
 
 type totpOptions struct {
-	algorithm Algorithm
-	digits    uint
-	stepSize  uint
-	account   string
-	issuer    string
+	algorithm    Algorithm
+	digits       uint
+	stepSize     uint
+	account      string
+	issuer       string
+	clock        Clock
+	epoch        int64
+	extensions   url.Values
+	offset       *uint8
+	alphabet     string
+	policy       *Policy
+	lockedMemory bool
 }
 
 type TotpOption func(*totpOptions)
@@ -70,6 +134,69 @@ func WithIssuer(issuer string) TotpOption {
 	}
 }
 
+// Use the clock as source for the current time.
+// By default the system time is used.
+func WithClock(clock Clock) TotpOption {
+	return func(to *totpOptions) {
+		to.clock = clock
+	}
+}
+
+// Count the time steps from the epoch instead of the unix epoch.
+// It is called T0 in RFC 6238 section 4.1.
+func WithEpoch(epoch time.Time) TotpOption {
+	return func(to *totpOptions) {
+		to.epoch = epoch.Unix()
+	}
+}
+
+// Alias for totp.WithEpoch
+func WithT0(t0 time.Time) TotpOption {
+	return WithEpoch(t0)
+}
+
+// Keep additional parameters of the Key URI, like the image or color
+// parameters of authenticator apps, so they are written back by ToUrl.
+func WithExtensions(extensions url.Values) TotpOption {
+	return func(to *totpOptions) {
+		to.extensions = extensions
+	}
+}
+
+// Extract the code at a fixed offset of the HMAC digest.
+// See hotp.WithTruncationOffset for details.
+func WithTruncationOffset(offset uint8) TotpOption {
+	return func(to *totpOptions) {
+		to.offset = &offset
+	}
+}
+
+// Encode the code with the characters of the alphabet.
+// See hotp.WithAlphabet for details.
+func WithAlphabet(alphabet string) TotpOption {
+	return func(to *totpOptions) {
+		to.alphabet = alphabet
+	}
+}
+
+// Reject keys, that violate the policy, with an error wrapping
+// ErrPolicyViolation. Verify rejects every code, if its window or drift
+// is larger than the policy allows.
+func WithPolicy(policy Policy) TotpOption {
+	return func(to *totpOptions) {
+		to.policy = &policy
+	}
+}
+
+// Keep the secret in memory, that is locked into RAM, so it is never
+// written to swap. This is only supported on Linux and has no effect
+// on other platforms.
+func WithLockedMemory() TotpOption {
+	return func(to *totpOptions) {
+		to.lockedMemory = true
+	}
+}
+
 const defaultDigits uint = 6
 const defaultStepSize uint = 30
 
@@ -81,90 +208,186 @@ var defaultAlgorithm Algorithm = Sha1
 type Totp struct {
 	hotp     *hotp.Hotp
 	stepSize uint
+	clock    Clock
+	epoch    int64
 }
 
 // Create a Totp instance from a base32 encoded secret.
 // The algorithm, that is usually used, is sha1.
+// See hotp.DecodeBase32 for the accepted formatting of the secret.
 //
 // Example:
 //
-//	totp := NewFromBase32("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
-//				WithAlgorithm(sha1.New),
+//	totp, err := NewFromBase32("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+//				WithAlgorithm(Sha1),
 //				WithDigits(6),
 //			)
 func NewFromBase32(secret string, options ...TotpOption) (*Totp, error) {
-	opts := &totpOptions{
-		algorithm: defaultAlgorithm,
-		digits:    defaultDigits,
-		stepSize:  defaultStepSize,
-	}
+	return NewFromEncoded(secret, EncodingBase32, options...)
+}
 
-	for _, option := range options {
-		option(opts)
+// Create a Totp instance from an encoded secret.
+// Use EncodingAuto to detect the encoding of the secret.
+//
+// Example:
+//
+//	totp, err := NewFromEncoded("3132333435363738393031323334353637383930", EncodingHex)
+func NewFromEncoded(secret string, encoding Encoding, options ...TotpOption) (*Totp, error) {
+	opts, err := newTotpOptions(options)
+	if err != nil {
+		return nil, err
 	}
 
-	hotp, err := hotp.NewFromBase32(
-		secret,
-		hotp.WithDigits(opts.digits),
-		hotp.WithAccount(opts.account),
-		hotp.WithIssuer(opts.issuer),
-		hotp.WithAlgorithm(opts.algorithm),
-	)
-
+	hotp, err := hotp.NewFromEncoded(secret, encoding, opts.toHotpOptions()...)
 	if err != nil {
 		return nil, err
 	}
+
 	return &Totp{
 		hotp:     hotp,
 		stepSize: opts.stepSize,
+		clock:    opts.clock,
+		epoch:    opts.epoch,
 	}, nil
 }
 
 // Create a Totp instance from a unencoded secret.
 // The algorithm, that is usually used, is sha1.
 //
+// An error is returned, if the algorithm is unknown, the amount of digits
+// is out of range, the step size is zero or the secret is too short.
+//
 // Example:
 //
-//	totp := New([]byte("12345678901234567890"),
-//				WithAlgorithm(sha1.New),
+//	totp, err := New([]byte("12345678901234567890"),
+//				WithAlgorithm(Sha1),
 //				WithDigits(6),
 //			)
-func New(secret []byte, options ...TotpOption) *Totp {
+func New(secret []byte, options ...TotpOption) (*Totp, error) {
+	opts, err := newTotpOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	hotp, err := hotp.New(secret, opts.toHotpOptions()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Totp{
+		hotp:     hotp,
+		stepSize: opts.stepSize,
+		clock:    opts.clock,
+		epoch:    opts.epoch,
+	}, nil
+}
+
+// Apply the options on top of the defaults and validate the
+// options, that are not validated by the hotp package.
+func newTotpOptions(options []TotpOption) (*totpOptions, error) {
 	opts := &totpOptions{
 		algorithm: defaultAlgorithm,
 		digits:    defaultDigits,
 		stepSize:  defaultStepSize,
+		clock:     defaultClock,
 	}
 
 	for _, option := range options {
 		option(opts)
 	}
 
-	hotp := hotp.New(secret,
+	if opts.clock == nil {
+		opts.clock = defaultClock
+	}
+
+	if opts.stepSize == 0 {
+		return nil, fmt.Errorf("%w: the step size has to be at least one second", ErrInvalidStepSize)
+	}
+
+	return opts, nil
+}
+
+func (opts *totpOptions) toHotpOptions() []hotp.HotpOption {
+	hotpOptions := []hotp.HotpOption{
 		hotp.WithDigits(opts.digits),
 		hotp.WithAccount(opts.account),
 		hotp.WithIssuer(opts.issuer),
 		hotp.WithAlgorithm(opts.algorithm),
-	)
+		hotp.WithExtensions(opts.extensions),
+		hotp.WithAlphabet(opts.alphabet),
+	}
 
-	return &Totp{
-		hotp:     hotp,
-		stepSize: opts.stepSize,
+	if opts.offset != nil {
+		hotpOptions = append(hotpOptions, hotp.WithTruncationOffset(*opts.offset))
+	}
+
+	if opts.policy != nil {
+		hotpOptions = append(hotpOptions, hotp.WithPolicy(*opts.policy))
 	}
+
+	if opts.lockedMemory {
+		hotpOptions = append(hotpOptions, hotp.WithLockedMemory())
+	}
+
+	return hotpOptions
 }
 
 func (t *Totp) Digits() uint {
 	return t.hotp.Digits()
 }
 
+// A copy of the unencoded secret.
+// The caller is responsible for zeroing it after use.
 func (t *Totp) Secret() []byte {
 	return t.hotp.Secret()
 }
 
+// Destroy zeroes the secret and the key schedule of HMAC.
+// See hotp.Hotp.Destroy for details.
+//
+// Afterwards, Calculate returns the zero Code and every verification fails.
+func (t *Totp) Destroy() {
+	t.hotp.Destroy()
+}
+
+// Whether Destroy was called on the instance.
+func (t *Totp) IsDestroyed() bool {
+	return t.hotp.IsDestroyed()
+}
+
+// Close implements io.Closer by calling Destroy.
+func (t *Totp) Close() error {
+	return t.hotp.Close()
+}
+
 func (t *Totp) Algorithm() Algorithm {
 	return t.hotp.Algorithm()
 }
 
+// The fingerprint of the secret, the algorithm, the digits and the period,
+// keyed with the key. See hotp.NewFingerprint for details.
+// A destroyed instance returns ErrDestroyed.
+func (t *Totp) Fingerprint(key []byte) (Fingerprint, error) {
+	if t.IsDestroyed() {
+		return Fingerprint{}, ErrDestroyed
+	}
+
+	secret := t.Secret()
+	defer hotp.Zero(secret)
+
+	return hotp.NewFingerprint(key, secret, t.Algorithm(), t.Digits(), t.stepSize)
+}
+
+// The policy of the instance, if one is set.
+func (t *Totp) Policy() (Policy, bool) {
+	return t.hotp.Policy()
+}
+
+// The size of the secret in bytes.
+func (t *Totp) SecretSize() uint {
+	return t.hotp.SecretSize()
+}
+
 func (t *Totp) StepSize() uint {
 	return t.stepSize
 }
@@ -185,109 +408,232 @@ func (t *Totp) Label() string {
 	return t.hotp.Label()
 }
 
-// TODO: Maybe change the output to a string and prepend the result with 0s
-func (t *Totp) Calculate(movingFactor uint64) uint32 {
-	flooredSeconds := float64(movingFactor)
-	movingFactor = uint64(math.Floor(flooredSeconds / float64(t.stepSize)))
-	return t.hotp.Calculate(movingFactor)
+// A copy of the additional parameters of the Key URI.
+func (t *Totp) Extensions() url.Values {
+	return t.hotp.Extensions()
 }
 
-// TODO: Maybe change the output to a string and prepend the result with 0s
-func (t *Totp) Now() uint32 {
-	unixSeconds := time.Now().Unix()
-	return t.Calculate(uint64(unixSeconds))
+// The fixed truncation offset, if one is set.
+func (t *Totp) TruncationOffset() (uint8, bool) {
+	return t.hotp.TruncationOffset()
 }
 
-// Alias for totp.Now()
-func (t *Totp) CalculateNow() uint32 {
-	return t.Now()
+// The alphabet of the codes.
+func (t *Totp) Alphabet() string {
+	return t.hotp.Alphabet()
 }
 
-// TODO: Add tests
-// References: https://docs.yubico.com/yesdk/users-manual/application-oath/uri-string-format.html
-// TODO: Look up other references (saved a bunch in otp on iPhone)
-func (t *Totp) ToUrl() string {
-	label := t.Account()
+// Parse a code entered by a user, using the digits and the alphabet
+// of the instance.
+func (t *Totp) ParseCode(input string) (Code, error) {
+	return t.hotp.ParseCode(input)
+}
+
+// Calculates the Totp code, taking the unix time in seconds as moving factor.
+func (t *Totp) Calculate(movingFactor uint64) Code {
+	return t.hotp.Calculate(t.stepAt(movingFactor))
+}
+
+// Calculates the Totp code for the given time.
+func (t *Totp) At(timestamp time.Time) Code {
+	return t.hotp.Calculate(t.step(timestamp))
+}
+
+// Calculates the Totp code for the current time of the clock.
+func (t *Totp) Now() Code {
+	return t.At(t.clock.Now())
+}
+
+func (t *Totp) Clock() Clock {
+	return t.clock
+}
+
+// The time, from which the time steps are counted.
+// It is called T0 in RFC 6238 section 4.1.
+func (t *Totp) Epoch() time.Time {
+	return time.Unix(t.epoch, 0)
+}
 
-	if t.Issuer() != "" {
-		label = label + ":" + t.Issuer()
+// Alias for totp.Epoch()
+func (t *Totp) T0() time.Time {
+	return t.Epoch()
+}
+
+// The index of the time step, the timestamp falls into.
+// Times before the epoch fall into the first time step.
+func (t *Totp) Step(timestamp time.Time) uint64 {
+	return t.step(timestamp)
+}
+
+// The time, at which the time step of the timestamp started.
+func (t *Totp) StepStart(timestamp time.Time) time.Time {
+	return t.stepStart(t.step(timestamp))
+}
+
+// The time, at which the time step of the timestamp ends
+// and the next code becomes valid.
+func (t *Totp) StepEnd(timestamp time.Time) time.Time {
+	return t.StepStart(timestamp).Add(time.Duration(t.stepSize) * time.Second)
+}
+
+// The duration, for which the code of the timestamp stays valid.
+func (t *Totp) Remaining(timestamp time.Time) time.Duration {
+	return t.StepEnd(timestamp).Sub(timestamp)
+}
+
+// Calculate the time, at which the time step starts.
+// The offset is added in seconds, as a time.Duration overflows
+// for times after the year 2262.
+func (t *Totp) stepStart(step uint64) time.Time {
+	offset := step * uint64(t.stepSize)
+	return time.Unix(t.epoch+int64(offset), 0)
+}
+
+// Calculate the time step of the unix time in seconds.
+// Unlike step, it covers the whole range of uint64.
+// Times before the epoch fall into the first time step.
+func (t *Totp) stepAt(movingFactor uint64) uint64 {
+	if t.epoch > 0 && movingFactor < uint64(t.epoch) {
+		return 0
 	}
 
-	otpUrl := &url.URL{
-		Scheme: "otpauth",
-		Host:   "totp",
-		Path:   label,
+	return (movingFactor - uint64(t.epoch)) / uint64(t.stepSize)
+}
+
+// The unix time in seconds, at which the time step starts.
+// Times after the range of uint64 are clamped to its max value.
+func (t *Totp) stepStartAt(step uint64) uint64 {
+	offset := step * uint64(t.stepSize)
+	if offset/uint64(t.stepSize) != step {
+		return ^uint64(0)
 	}
 
-	encodedSecret := base32.StdEncoding.EncodeToString(t.Secret())
+	start := offset + uint64(t.epoch)
+	if t.epoch > 0 && start < offset {
+		return ^uint64(0)
+	}
 
-	query := otpUrl.Query()
+	return start
+}
 
-	query.Set("secret", encodedSecret)
-	query.Set("period", fmt.Sprint(t.StepSize()))
+// Calculate the time step of the timestamp.
+// Times before the epoch fall into the first time step.
+func (t *Totp) step(timestamp time.Time) uint64 {
+	seconds := timestamp.Unix()
+	if seconds < t.epoch {
+		return 0
+	}
 
-	query.Set("algorithm", string(t.Algorithm()))
+	return uint64(seconds-t.epoch) / uint64(t.stepSize)
+}
 
-	query.Set("digits", fmt.Sprint(t.Digits()))
+// Alias for totp.Now()
+func (t *Totp) CalculateNow() Code {
+	return t.Now()
+}
 
-	if t.Issuer() != "" {
-		query.Set("issuer", t.Issuer())
+// Format the instance as otpauth Key URI.
+// The secret of a destroyed instance is empty.
+func (t *Totp) ToUrl() string {
+	secret := t.Secret()
+	defer hotp.Zero(secret)
+
+	key := &uri.Key{
+		Type:       uri.TypeTotp,
+		Issuer:     t.Issuer(),
+		Account:    t.Account(),
+		Secret:     secret,
+		Algorithm:  string(t.Algorithm()),
+		Digits:     t.Digits(),
+		Period:     t.StepSize(),
+		T0:         t.epoch,
+		Alphabet:   t.alphabet(),
+		Extensions: t.Extensions(),
 	}
 
-	otpUrl.RawQuery = query.Encode()
+	offset, hasOffset := t.TruncationOffset()
+	if hasOffset {
+		key.Offset = &offset
+	}
 
-	return otpUrl.String()
+	return key.String()
 }
 
-func NewFromUrl(rawUrl string) (*Totp, error) {
-	otpUrl, err := url.Parse(rawUrl)
+// Create a Totp instance from an otpauth url.
+// Legacy urls, with the issuer after the account, are accepted as well.
+// The options are applied after the values of the url.
+func NewFromUrl(rawUrl string, options ...TotpOption) (*Totp, error) {
+	key, err := uri.ParseLenient(rawUrl)
 	if err != nil {
 		return nil, err
 	}
+	defer hotp.Zero(key.Secret)
 
-	encodedSecret := otpUrl.Query().Get("secret")
+	return NewFromKey(key, options...)
+}
 
-	totpOptions := []TotpOption{}
+// Create a Totp instance from a parsed otpauth url.
+// The options are applied after the values of the key.
+//
+// The secret of the key is copied, so the caller can zero it afterwards.
+func NewFromKey(key *uri.Key, options ...TotpOption) (*Totp, error) {
+	if key.Type != uri.TypeTotp {
+		return nil, fmt.Errorf("%w: expected %q, got %q", uri.ErrInvalidType, uri.TypeTotp, key.Type)
+	}
 
-	label := otpUrl.Path
-	label = strings.TrimPrefix(label, "/")
-	account, _, _ := strings.Cut(label, ":")
-	if account != "" {
-		totpOptions = append(totpOptions, WithAccount(account))
+	totpOptions, err := optionsFromKey(key)
+	if err != nil {
+		return nil, err
 	}
 
-	periodAsString := otpUrl.Query().Get("period")
-	if periodAsString != "" {
-		periodAsInt, err := strconv.Atoi(periodAsString)
-		if err != nil {
-			return nil, err
-		}
+	totpOptions = append(totpOptions, options...)
+
+	return New(key.Secret, totpOptions...)
+}
 
-		period := uint(periodAsInt)
-		totpOptions = append(totpOptions, WithStepSize(period))
+// The options, that are described by the parameters of the Key URI.
+// Parameters, that are not set, are left out so the defaults apply.
+func optionsFromKey(key *uri.Key) ([]TotpOption, error) {
+	totpOptions := []TotpOption{
+		WithAccount(key.Account),
+		WithIssuer(key.Issuer),
+		WithEpoch(time.Unix(key.T0, 0)),
+		WithExtensions(key.Extensions),
 	}
 
-	digitsAsString := otpUrl.Query().Get("digits")
-	if digitsAsString != "" {
-		digitsAsInt, err := strconv.Atoi(digitsAsString)
+	if key.Digits != 0 {
+		totpOptions = append(totpOptions, WithDigits(key.Digits))
+	}
+
+	if key.Period != 0 {
+		totpOptions = append(totpOptions, WithStepSize(key.Period))
+	}
+
+	if key.Offset != nil {
+		totpOptions = append(totpOptions, WithTruncationOffset(*key.Offset))
+	}
+
+	if key.Alphabet != "" {
+		totpOptions = append(totpOptions, WithAlphabet(key.Alphabet))
+	}
+
+	if key.Algorithm != "" {
+		algorithm, err := ParseAlgorithm(key.Algorithm)
 		if err != nil {
 			return nil, err
 		}
 
-		digits := uint(digitsAsInt)
-		totpOptions = append(totpOptions, WithDigits(digits))
+		totpOptions = append(totpOptions, WithAlgorithm(algorithm))
 	}
 
-	issuer := otpUrl.Query().Get("issuer")
-	if issuer != "" {
-		totpOptions = append(totpOptions, WithIssuer(issuer))
-	}
+	return totpOptions, nil
+}
 
-	algorithm := otpUrl.Query().Get("algorithm")
-	if algorithm == "" {
-		algorithm = string(Sha1)
+// The alphabet for the Key URI, which is empty for decimal codes
+func (t *Totp) alphabet() string {
+	if t.Alphabet() == Decimal {
+		return ""
 	}
-	totpOptions = append(totpOptions, WithAlgorithm(hotp.Algorithm(algorithm)))
 
-	return NewFromBase32(encodedSecret, totpOptions...)
+	return t.Alphabet()
 }
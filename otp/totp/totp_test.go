@@ -1,9 +1,16 @@
 package totp_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"bode.fun/otp/hotp"
 	"bode.fun/otp/totp"
+	"bode.fun/otp/uri"
 	"github.com/matryer/is"
 )
 
@@ -14,39 +21,40 @@ import (
 // It just checks sha1
 func Test_Rfc6238_Sha1(t *testing.T) {
 	is := is.New(t)
-	totp := totp.New(
+	totp, err := totp.New(
 		[]byte("12345678901234567890"),
 		totp.WithDigits(8),
 	)
+	is.NoErr(err)
 
 	{
 		code := totp.Calculate(59)
-		is.Equal(uint32(94287082), code)
+		is.Equal("94287082", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111109)
-		is.Equal(uint32(7081804), code) // 07081804
+		is.Equal("07081804", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111111)
-		is.Equal(uint32(14050471), code)
+		is.Equal("14050471", code.String())
 	}
 
 	{
 		code := totp.Calculate(1234567890)
-		is.Equal(uint32(89005924), code)
+		is.Equal("89005924", code.String())
 	}
 
 	{
 		code := totp.Calculate(2000000000)
-		is.Equal(uint32(69279037), code)
+		is.Equal("69279037", code.String())
 	}
 
 	{
 		code := totp.Calculate(20000000000)
-		is.Equal(uint32(65353130), code)
+		is.Equal("65353130", code.String())
 	}
 }
 
@@ -57,40 +65,41 @@ func Test_Rfc6238_Sha1(t *testing.T) {
 // It just checks sha256
 func Test_Rfc6238_Sha256(t *testing.T) {
 	is := is.New(t)
-	totp := totp.New(
+	totp, err := totp.New(
 		[]byte("12345678901234567890123456789012"),
 		totp.WithAlgorithm(totp.Sha256),
 		totp.WithDigits(8),
 	)
+	is.NoErr(err)
 
 	{
 		code := totp.Calculate(59)
-		is.Equal(uint32(46119246), code)
+		is.Equal("46119246", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111109)
-		is.Equal(uint32(68084774), code)
+		is.Equal("68084774", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111111)
-		is.Equal(uint32(67062674), code)
+		is.Equal("67062674", code.String())
 	}
 
 	{
 		code := totp.Calculate(1234567890)
-		is.Equal(uint32(91819424), code)
+		is.Equal("91819424", code.String())
 	}
 
 	{
 		code := totp.Calculate(2000000000)
-		is.Equal(uint32(90698825), code)
+		is.Equal("90698825", code.String())
 	}
 
 	{
 		code := totp.Calculate(20000000000)
-		is.Equal(uint32(77737706), code)
+		is.Equal("77737706", code.String())
 	}
 }
 
@@ -101,39 +110,649 @@ func Test_Rfc6238_Sha256(t *testing.T) {
 // It just checks sha512
 func Test_Rfc6238_Sha512(t *testing.T) {
 	is := is.New(t)
-	totp := totp.New(
+	totp, err := totp.New(
 		[]byte("1234567890123456789012345678901234567890123456789012345678901234"),
 		totp.WithAlgorithm(totp.Sha512),
 		totp.WithDigits(8),
 	)
+	is.NoErr(err)
 
 	{
 		code := totp.Calculate(59)
-		is.Equal(uint32(90693936), code)
+		is.Equal("90693936", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111109)
-		is.Equal(uint32(25091201), code)
+		is.Equal("25091201", code.String())
 	}
 
 	{
 		code := totp.Calculate(1111111111)
-		is.Equal(uint32(99943326), code)
+		is.Equal("99943326", code.String())
 	}
 
 	{
 		code := totp.Calculate(1234567890)
-		is.Equal(uint32(93441116), code)
+		is.Equal("93441116", code.String())
 	}
 
 	{
 		code := totp.Calculate(2000000000)
-		is.Equal(uint32(38618901), code)
+		is.Equal("38618901", code.String())
 	}
 
 	{
 		code := totp.Calculate(20000000000)
-		is.Equal(uint32(47863826), code)
+		is.Equal("47863826", code.String())
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+	)
+	is.NoErr(err)
+
+	timestamp := time.Unix(1111111109, 0)
+	currentStep := uint64(1111111109 / 30)
+
+	code, err := totp.ParseCode("070 818 04", 8)
+	is.NoErr(err)
+	otherCode, err := totp.ParseCode("12345678", 8)
+	is.NoErr(err)
+
+	{
+		step, ok := totpInstance.Verify(code, timestamp)
+		is.True(ok)
+		is.Equal(currentStep, step)
+	}
+
+	{
+		// The code of the previous step is accepted by the default skew
+		step, ok := totpInstance.Verify(code, timestamp.Add(30*time.Second))
+		is.True(ok)
+		is.Equal(currentStep, step)
+	}
+
+	{
+		_, ok := totpInstance.Verify(code, timestamp.Add(60*time.Second))
+		is.True(!ok)
+	}
+
+	{
+		step, ok := totpInstance.Verify(code, timestamp.Add(60*time.Second), totp.WithPastSteps(2))
+		is.True(ok)
+		is.Equal(currentStep, step)
+	}
+
+	{
+		_, ok := totpInstance.Verify(code, timestamp.Add(-30*time.Second), totp.WithFutureSteps(0))
+		is.True(!ok)
+	}
+
+	{
+		_, ok := totpInstance.Verify(code, timestamp.Add(30*time.Second), totp.WithSkew(0))
+		is.True(!ok)
+	}
+
+	{
+		_, ok := totpInstance.Verify(otherCode, timestamp)
+		is.True(!ok)
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	is := is.New(t)
+
+	totpInstance, encodedSecret, err := totp.Generate(
+		totp.WithAlgorithm(totp.Sha256),
+		totp.WithDigits(8),
+	)
+	is.NoErr(err)
+	is.Equal(32, len(totpInstance.Secret()))
+	is.Equal(uint(8), totpInstance.Digits())
+
+	decoded, err := totp.NewFromBase32(encodedSecret)
+	is.NoErr(err)
+	is.Equal(totpInstance.Secret(), decoded.Secret())
+}
+
+func Test_Validation(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	{
+		_, err := totp.New(secret, totp.WithPeriod(0))
+		is.True(errors.Is(err, totp.ErrInvalidStepSize))
+	}
+
+	{
+		_, err := totp.New(secret, totp.WithDigits(4))
+		is.True(errors.Is(err, totp.ErrInvalidDigits))
+	}
+
+	{
+		_, err := totp.NewFromUrl("otpauth://totp/account?secret=GEZDGNBVGY3TQOJQ&period=0")
+		is.True(errors.Is(err, uri.ErrInvalidParameter))
+	}
+
+	{
+		totpInstance, err := totp.NewFromUrl("otpauth://totp/account?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&algorithm=SHA512")
+		is.NoErr(err)
+		is.Equal(totp.Sha512, totpInstance.Algorithm())
+	}
+}
+
+func Test_Clock(t *testing.T) {
+	is := is.New(t)
+	now := time.Unix(1111111109, 0)
+
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+		totp.WithClock(totp.ClockFunc(func() time.Time {
+			return now
+		})),
+	)
+	is.NoErr(err)
+
+	is.Equal("07081804", totpInstance.Now().String())
+	is.Equal("07081804", totpInstance.At(now).String())
+	is.Equal("14050471", totpInstance.At(time.Unix(1111111111, 0)).String())
+
+	code, err := totp.ParseCode("07081804", 8)
+	is.NoErr(err)
+	is.True(totpInstance.Validate(code))
+
+	now = now.Add(90 * time.Second)
+	is.True(!totpInstance.Validate(code))
+}
+
+func Test_LargeTimestamp(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+
+	totpInstance, err := totp.New(secret, totp.WithStepSize(1))
+	is.NoErr(err)
+
+	hotpInstance, err := hotp.New(secret)
+	is.NoErr(err)
+
+	// float64 can not represent every integer above 2^53
+	timestamp := uint64(1<<53 + 1)
+	is.Equal(hotpInstance.Calculate(timestamp), totpInstance.Calculate(timestamp))
+}
+
+func Test_Epoch(t *testing.T) {
+	is := is.New(t)
+	epoch := time.Unix(1000000000, 0)
+
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+		totp.WithT0(epoch),
+	)
+	is.NoErr(err)
+	is.Equal(epoch, totpInstance.Epoch())
+
+	is.Equal("94287082", totpInstance.At(epoch.Add(59*time.Second)).String())
+	is.Equal("94287082", totpInstance.Calculate(1000000059).String())
+
+	code, err := totp.ParseCode("94287082", 8)
+	is.NoErr(err)
+	step, ok := totpInstance.Verify(code, epoch.Add(59*time.Second))
+	is.True(ok)
+	is.Equal(uint64(1), step)
+
+	parsed, err := totp.NewFromUrl(totpInstance.ToUrl())
+	is.NoErr(err)
+	is.Equal(epoch, parsed.Epoch())
+	is.Equal("94287082", parsed.At(epoch.Add(59*time.Second)).String())
+}
+
+func Test_StepIntrospection(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New([]byte("12345678901234567890"))
+	is.NoErr(err)
+
+	timestamp := time.Unix(1111111109, 500000000)
+
+	is.Equal(uint64(37037036), totpInstance.Step(timestamp))
+	is.Equal(time.Unix(1111111080, 0), totpInstance.StepStart(timestamp))
+	is.Equal(time.Unix(1111111110, 0), totpInstance.StepEnd(timestamp))
+	is.Equal(500*time.Millisecond, totpInstance.Remaining(timestamp))
+
+	// The last second of the step still belongs to it
+	is.Equal(uint64(37037036), totpInstance.Step(time.Unix(1111111109, 999999999)))
+	is.Equal(uint64(37037037), totpInstance.Step(time.Unix(1111111110, 0)))
+
+	{
+		// The last test time of RFC 6238 is after the max time.Duration
+		timestamp := time.Unix(20000000000, 0)
+
+		is.Equal(uint64(666666666), totpInstance.Step(timestamp))
+		is.Equal(time.Unix(19999999980, 0), totpInstance.StepStart(timestamp))
+		is.Equal(time.Unix(20000000010, 0), totpInstance.StepEnd(timestamp))
+		is.Equal(10*time.Second, totpInstance.Remaining(timestamp))
+	}
+}
+
+func Test_Stream(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithStepSize(1),
+	)
+	is.NoErr(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	codes := totpInstance.Stream(ctx)
+
+	first := <-codes
+	is.Equal(first.Code, totpInstance.At(first.Start))
+	is.Equal(first.Start.Add(time.Second), first.End)
+
+	second := <-codes
+	is.Equal(first.Step+1, second.Step)
+	is.Equal(first.End, second.Start)
+
+	cancel()
+
+	// The channel gets closed after the cancellation
+	for range codes {
+	}
+
+	{
+		// Times after the max time.Duration keep valid intervals
+		clockInstance := totp.ClockFunc(func() time.Time {
+			return time.Unix(20000000000, 0)
+		})
+
+		totpInstance, err := totp.New([]byte("12345678901234567890"), totp.WithClock(clockInstance))
+		is.NoErr(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		codes := totpInstance.Stream(ctx)
+
+		first := <-codes
+		is.Equal(time.Unix(19999999980, 0), first.Start)
+		is.Equal(time.Unix(20000000010, 0), first.End)
+
+		cancel()
+
+		for range codes {
+		}
+	}
+}
+
+func Test_VerifyWithDrift(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+	)
+	is.NoErr(err)
+
+	code, err := totp.ParseCode("07081804", 8)
+	is.NoErr(err)
+	codeTime := time.Unix(1111111109, 0)
+
+	{
+		// The phone runs two steps ahead, which is outside of the default window
+		serverTime := codeTime.Add(-60 * time.Second)
+		_, ok := totpInstance.Verify(code, serverTime)
+		is.True(!ok)
+
+		drift, ok := totpInstance.VerifyWithDrift(code, serverTime, 1)
+		is.True(ok)
+		is.Equal(int64(2), drift)
+	}
+
+	{
+		// The phone runs three steps behind
+		serverTime := codeTime.Add(90 * time.Second)
+		drift, ok := totpInstance.VerifyWithDrift(code, serverTime, -2)
+		is.True(ok)
+		is.Equal(int64(-3), drift)
+	}
+
+	{
+		// The drift is kept, if the code is rejected
+		drift, ok := totpInstance.VerifyWithDrift(code, codeTime.Add(300*time.Second), 4)
+		is.True(!ok)
+		is.Equal(int64(4), drift)
+	}
+}
+
+func Test_CodesBetween(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+	)
+	is.NoErr(err)
+
+	codes, err := totpInstance.CodesBetween(time.Unix(1111111050, 0), time.Unix(1111111111, 0))
+	is.NoErr(err)
+	is.Equal(3, len(codes))
+
+	is.Equal(time.Unix(1111111050, 0), codes[0].Start)
+	is.Equal("07081804", codes[1].Code.String())
+	is.Equal("14050471", codes[2].Code.String())
+	is.Equal(codes[1].End, codes[2].Start)
+	is.Equal(codes[1].Step+1, codes[2].Step)
+
+	codes, err = totpInstance.CodesBetween(time.Unix(60, 0), time.Unix(0, 0))
+	is.NoErr(err)
+	is.Equal(0, len(codes))
+
+	{
+		codes, err := totpInstance.CodesBetween(time.Unix(20000000000, 0), time.Unix(20000000030, 0))
+		is.NoErr(err)
+		is.Equal(2, len(codes))
+		is.Equal(time.Unix(19999999980, 0), codes[0].Start)
+		is.Equal(time.Unix(20000000040, 0), codes[1].End)
+	}
+
+	{
+		_, err := totpInstance.CodesBetween(time.Time{}, time.Now())
+		is.True(errors.Is(err, totp.ErrTooManySteps))
+
+		// The amount of steps would overflow
+		_, err = totpInstance.CodesBetween(time.Unix(0, 0), time.Unix(1<<62, 0))
+		is.True(errors.Is(err, totp.ErrTooManySteps))
+
+		codes, err := totpInstance.CodesBetween(time.Unix(0, 0), time.Unix(totp.MaxCodesBetween*30-1, 0))
+		is.NoErr(err)
+		is.Equal(totp.MaxCodesBetween, len(codes))
+	}
+}
+
+// Unix times beyond the range of int64 are valid moving factors
+func Test_LargeMovingFactor(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New([]byte("12345678901234567890"))
+	is.NoErr(err)
+
+	for _, movingFactor := range []uint64{1 << 63, ^uint64(0)} {
+		code := totpInstance.Calculate(movingFactor)
+		is.True(totpInstance.Check(code, movingFactor))
+		is.True(!totpInstance.Check(code, movingFactor-1000))
+
+		next, isValid := totpInstance.VerifyAt(code, movingFactor)
+		is.True(isValid)
+		is.True(next > movingFactor || next == ^uint64(0))
+	}
+
+	// The step after 1<<63 starts at the next multiple of the period
+	next, isValid := totpInstance.VerifyAt(totpInstance.Calculate(1<<63), 1<<63)
+	is.True(isValid)
+	is.Equal(uint64(1<<63)/30*30+30, next)
+}
+
+func Test_Destroy(t *testing.T) {
+	is := is.New(t)
+
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+		totp.WithLockedMemory(),
+	)
+	is.NoErr(err)
+	code := totpInstance.Calculate(59)
+	is.Equal("94287082", code.String())
+
+	is.NoErr(totpInstance.Close())
+	is.Equal(0, len(totpInstance.Secret()))
+
+	is.True(!totpInstance.Check(code, 59))
+	is.True(!totpInstance.Check(totpInstance.Calculate(59), 59))
+
+	_, err = totpInstance.MarshalText()
+	is.True(errors.Is(err, totp.ErrDestroyed))
+
+	_, err = json.Marshal(totpInstance)
+	is.True(errors.Is(err, totp.ErrDestroyed))
+}
+
+func Test_Encoding(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithDigits(8),
+		totp.WithPeriod(60),
+		totp.WithAccount("alice@example.com"),
+	)
+	is.NoErr(err)
+
+	{
+		data, err := json.Marshal(totpInstance)
+		is.NoErr(err)
+		is.Equal(`{"type":"totp","secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","algorithm":"sha1","digits":8,"period":60,"account":"alice@example.com"}`, string(data))
+
+		var parsed totp.Totp
+		is.NoErr(json.Unmarshal(data, &parsed))
+		is.Equal(totpInstance.Secret(), parsed.Secret())
+		is.Equal(uint(60), parsed.Period())
+		is.Equal(totpInstance.Calculate(59), parsed.Calculate(59))
+	}
+
+	{
+		text, err := totpInstance.MarshalText()
+		is.NoErr(err)
+
+		var parsed totp.Totp
+		is.NoErr(parsed.UnmarshalText(text))
+		is.Equal(uint(8), parsed.Digits())
+		is.Equal(totpInstance.Calculate(59), parsed.Calculate(59))
+	}
+}
+
+func Test_Url(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithIssuer("ACME Co"),
+		totp.WithAccount("john.doe@email.com"),
+	)
+	is.NoErr(err)
+
+	is.Equal("ACME%20Co:john.doe@email.com", totpInstance.Label())
+	is.Equal(
+		"otpauth://totp/ACME%20Co:john.doe@email.com?algorithm=SHA1&digits=6&issuer=ACME%20Co&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		totpInstance.ToUrl(),
+	)
+
+	{
+		parsed, err := totp.NewFromUrl(totpInstance.ToUrl())
+		is.NoErr(err)
+		is.Equal("ACME Co", parsed.Issuer())
+		is.Equal("john.doe@email.com", parsed.Account())
+	}
+
+	{
+		// Urls of older versions put the issuer after the account
+		parsed, err := totp.NewFromUrl("otpauth://totp/john.doe@email.com:ACME%20Co?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ%3D%3D%3D%3D&issuer=ACME+Co&algorithm=sha1&digits=6&period=30")
+		is.NoErr(err)
+		is.Equal("ACME Co", parsed.Issuer())
+		is.Equal("john.doe@email.com", parsed.Account())
+	}
+
+	{
+		_, err := totp.NewFromUrl("otpauth://hotp/john.doe@email.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0")
+		is.True(errors.Is(err, uri.ErrInvalidType))
+	}
+}
+
+func Test_Extensions(t *testing.T) {
+	is := is.New(t)
+
+	rawUrl := "otpauth://totp/Example:alice@google.com?algorithm=SHA1&color=%23ff0000&digits=6&image=https%3A%2F%2Fexample.com%2Flogo.png&issuer=Example&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	totpInstance, err := totp.NewFromUrl(rawUrl)
+	is.NoErr(err)
+	is.Equal("https://example.com/logo.png", totpInstance.Extensions().Get("image"))
+	is.Equal(rawUrl, totpInstance.ToUrl())
+
+	// The returned parameters are a copy
+	totpInstance.Extensions().Set("color", "#00ff00")
+	is.Equal("#ff0000", totpInstance.Extensions().Get("color"))
+
+	data, err := json.Marshal(totpInstance)
+	is.NoErr(err)
+
+	var parsed totp.Totp
+	is.NoErr(json.Unmarshal(data, &parsed))
+	is.Equal(rawUrl, parsed.ToUrl())
+}
+
+func Test_Truncation(t *testing.T) {
+	is := is.New(t)
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890"),
+		totp.WithTruncationOffset(4),
+		totp.WithAlphabet(totp.Hexadecimal),
+	)
+	is.NoErr(err)
+
+	is.Equal(
+		"otpauth://totp/?algorithm=SHA1&alphabet=0123456789ABCDEF&digits=6&offset=4&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		totpInstance.ToUrl(),
+	)
+
+	code := totpInstance.At(time.Unix(59, 0))
+	is.Equal(totp.Hexadecimal, code.Alphabet())
+
+	parsedCode, err := totpInstance.ParseCode(strings.ToLower(code.String()))
+	is.NoErr(err)
+	_, isValid := totpInstance.Verify(parsedCode, time.Unix(59, 0))
+	is.True(isValid)
+
+	{
+		parsed, err := totp.NewFromUrl(totpInstance.ToUrl())
+		is.NoErr(err)
+		is.Equal(code, parsed.At(time.Unix(59, 0)))
+	}
+
+	{
+		data, err := json.Marshal(totpInstance)
+		is.NoErr(err)
+
+		var parsed totp.Totp
+		is.NoErr(json.Unmarshal(data, &parsed))
+		is.Equal(code, parsed.At(time.Unix(59, 0)))
+
+		offset, hasOffset := parsed.TruncationOffset()
+		is.True(hasOffset)
+		is.Equal(uint8(4), offset)
+	}
+}
+
+func Test_SecretEncodings(t *testing.T) {
+	is := is.New(t)
+
+	for _, encodedSecret := range []string{
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"3132333435363738393031323334353637383930",
+		"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=",
+	} {
+		totpInstance, err := totp.NewFromEncoded(encodedSecret, totp.EncodingAuto, totp.WithDigits(8))
+		is.NoErr(err)
+		is.Equal("94287082", totpInstance.At(time.Unix(59, 0)).String())
+	}
+
+	_, err := totp.NewFromEncoded("3132", totp.EncodingHex)
+	is.True(errors.Is(err, totp.ErrSecretTooShort))
+}
+
+func Test_Fingerprint(t *testing.T) {
+	is := is.New(t)
+	secret := []byte("12345678901234567890")
+	key := []byte("0123456789abcdef")
+
+	totpInstance, err := totp.New(secret, totp.WithDigits(8), totp.WithAccount("alice"))
+	is.NoErr(err)
+
+	fingerprint, err := totpInstance.Fingerprint(key)
+	is.NoErr(err)
+	is.Equal("V6LO-L5IJ-WL5Y-IZYL", fingerprint.String())
+
+	parsed, err := totp.NewFromUrl(totpInstance.ToUrl())
+	is.NoErr(err)
+
+	parsedFingerprint, err := parsed.Fingerprint(key)
+	is.NoErr(err)
+	is.True(fingerprint.Equal(parsedFingerprint))
+
+	other, err := totp.New(secret, totp.WithDigits(8), totp.WithPeriod(60))
+	is.NoErr(err)
+
+	otherFingerprint, err := other.Fingerprint(key)
+	is.NoErr(err)
+	is.True(!fingerprint.Equal(otherFingerprint))
+
+	_, err = other.Fingerprint([]byte("short"))
+	is.True(errors.Is(err, totp.ErrFingerprintKeyTooShort))
+
+	other.Destroy()
+	_, err = other.Fingerprint(key)
+	is.True(errors.Is(err, totp.ErrDestroyed))
+}
+
+func Test_Policy(t *testing.T) {
+	is := is.New(t)
+
+	policy := totp.Policy{
+		ForbiddenAlgorithms: []totp.Algorithm{totp.Sha1},
+		MaxWindow:           1,
+		MaxDrift:            2,
+	}
+
+	{
+		_, err := totp.New([]byte("12345678901234567890"), totp.WithPolicy(policy))
+		is.True(errors.Is(err, totp.ErrPolicyViolation))
+	}
+
+	totpInstance, err := totp.New(
+		[]byte("12345678901234567890123456789012"),
+		totp.WithAlgorithm(totp.Sha256),
+		totp.WithPolicy(policy),
+	)
+	is.NoErr(err)
+
+	now := time.Unix(1111111109, 0)
+	code := totpInstance.At(now.Add(-60 * time.Second))
+
+	// A larger window than the policy allows is rejected
+	_, isValid := totpInstance.Verify(code, now, totp.WithSkew(2))
+	is.True(!isValid)
+
+	_, isValid = totpInstance.Verify(totpInstance.At(now), now, totp.WithSkew(2))
+	is.True(!isValid)
+
+	_, isValid = totpInstance.Verify(totpInstance.At(now), now)
+	is.True(isValid)
+
+	// So is a larger drift
+	_, isValid = totpInstance.VerifyWithDrift(code, now, -2)
+	is.True(isValid)
+
+	_, isValid = totpInstance.VerifyWithDrift(totpInstance.At(now.Add(90*time.Second)), now, 3)
+	is.True(!isValid)
+
+	{
+		other, err := totp.New([]byte("12345678901234567890"))
+		is.NoErr(err)
+
+		code := other.At(now.Add(-60 * time.Second))
+
+		_, isValid := other.Verify(code, now, totp.WithSkew(2))
+		is.True(isValid)
+
+		_, isValid = other.Verify(code, now, totp.WithSkew(2), totp.WithVerifyPolicy(policy))
+		is.True(!isValid)
 	}
 }
@@ -0,0 +1,222 @@
+package totp
+
+import (
+	"time"
+)
+
+type verifyOptions struct {
+	past   uint
+	future uint
+	drift  int64
+	policy *Policy
+}
+
+type VerifyOption func(*verifyOptions)
+
+// Accept codes from up to skew time steps before and after the current one.
+func WithSkew(skew uint) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.past = skew
+		vo.future = skew
+	}
+}
+
+// Accept codes from up to steps time steps before the current one.
+func WithPastSteps(steps uint) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.past = steps
+	}
+}
+
+// Accept codes from up to steps time steps after the current one.
+func WithFutureSteps(steps uint) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.future = steps
+	}
+}
+
+// Centre the window on the current time step shifted by drift steps.
+// A positive drift is used for clocks, that run ahead.
+func WithDrift(drift int64) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.drift = drift
+	}
+}
+
+// Reject every code, if the window or the drift is larger than the policy
+// allows. The policy of the instance is applied as well.
+func WithVerifyPolicy(policy Policy) VerifyOption {
+	return func(vo *verifyOptions) {
+		vo.policy = &policy
+	}
+}
+
+const defaultSkew uint = 1
+
+// Verify checks a code against the time step of timestamp and the
+// surrounding steps of the window. By default one step in each direction
+// is accepted, as recommended by RFC 6238 section 5.2.
+//
+// On success the matched time step is returned. Callers should store it
+// and reject any later code for the same or an earlier step to prevent
+// replay attacks.
+//
+// If the window in either direction or the drift is larger than the
+// policy of the instance or of WithVerifyPolicy allows, every code is
+// rejected. Use CheckWindow and CheckDrift of the policy to find the
+// violation.
+//
+// Every step of the window is checked and the codes are compared in
+// constant time, so the duration does not leak which step matched.
+func (t *Totp) Verify(code Code, timestamp time.Time, options ...VerifyOption) (uint64, bool) {
+	return t.verifyStep(code, t.step(timestamp), options...)
+}
+
+// Verify the code against the time step and the surrounding steps.
+func (t *Totp) verifyStep(code Code, step uint64, options ...VerifyOption) (uint64, bool) {
+	opts := &verifyOptions{
+		past:   defaultSkew,
+		future: defaultSkew,
+	}
+
+	for _, option := range options {
+		option(opts)
+	}
+
+	policy, hasPolicy := t.Policy()
+	if hasPolicy && !opts.isAllowedBy(policy) {
+		return 0, false
+	}
+
+	if opts.policy != nil && !opts.isAllowedBy(*opts.policy) {
+		return 0, false
+	}
+
+	// The keyed state of a destroyed instance uses an empty key
+	if t.hotp.IsDestroyed() {
+		return 0, false
+	}
+
+	current := shiftStep(step, opts.drift)
+
+	first := uint64(0)
+	if current > uint64(opts.past) {
+		first = current - uint64(opts.past)
+	}
+
+	last := current + uint64(opts.future)
+	if last < current {
+		last = ^uint64(0)
+	}
+
+	var matchedStep uint64
+	matched := false
+
+	for step := first; ; step++ {
+		isEqual := t.hotp.Calculate(step).Equal(code)
+		if isEqual && !matched {
+			matchedStep = step
+			matched = true
+		}
+
+		if step == last {
+			break
+		}
+	}
+
+	return matchedStep, matched
+}
+
+// Whether the policy allows the past and future steps and the drift
+func (vo *verifyOptions) isAllowedBy(policy Policy) bool {
+	violations := policy.CheckWindow(vo.past)
+	violations = append(violations, policy.CheckWindow(vo.future)...)
+	violations = append(violations, policy.CheckDrift(vo.drift)...)
+
+	return len(violations) == 0
+}
+
+// Validate reports whether code is valid at the current time of the clock.
+// It accepts the same options as Verify.
+func (t *Totp) Validate(code Code, options ...VerifyOption) bool {
+	_, ok := t.Verify(code, t.clock.Now(), options...)
+	return ok
+}
+
+// VerifyWithDrift checks a code like Verify, but centres the window on
+// the drift, that was learned from previous verifications, as suggested
+// by RFC 6238 section 6.
+//
+// On success the updated drift is returned. It is the amount of time
+// steps between the matched step and the step of timestamp and should
+// be stored per user for the next verification. Set MaxDrift of the
+// policy to bound the drift, that is accepted.
+//
+// Example:
+//
+//	drift, ok := totp.VerifyWithDrift(code, time.Now(), user.Drift)
+//	if ok {
+//		user.Drift = drift
+//	}
+func (t *Totp) VerifyWithDrift(code Code, timestamp time.Time, drift int64, options ...VerifyOption) (int64, bool) {
+	options = append(options, WithDrift(drift))
+
+	matchedStep, ok := t.Verify(code, timestamp, options...)
+	if !ok {
+		return drift, false
+	}
+
+	current := t.step(timestamp)
+	if matchedStep >= current {
+		return int64(matchedStep - current), true
+	}
+
+	return -int64(current - matchedStep), true
+}
+
+// Move the step by drift steps without leaving the range of uint64
+func shiftStep(step uint64, drift int64) uint64 {
+	if drift >= 0 {
+		shifted := step + uint64(drift)
+		if shifted < step {
+			return ^uint64(0)
+		}
+
+		return shifted
+	}
+
+	backwards := uint64(-drift)
+	if backwards > step {
+		return 0
+	}
+
+	return step - backwards
+}
+
+// Check reports whether the code is valid at the unix time in seconds.
+// It uses the default window of Verify.
+func (t *Totp) Check(code Code, movingFactor uint64) bool {
+	_, ok := t.verifyStep(code, t.stepAt(movingFactor))
+	return ok
+}
+
+// VerifyAt verifies the code at the unix time in seconds like Verify with
+// the default window. It implements the Verifier interface of the otp
+// package.
+//
+// On success the unix time, at which the step after the matched one
+// starts, is returned. Store it and reject later codes, whose returned
+// time is not after the stored one, to prevent replay attacks.
+func (t *Totp) VerifyAt(code Code, movingFactor uint64) (uint64, bool) {
+	matchedStep, ok := t.verifyStep(code, t.stepAt(movingFactor))
+	if !ok {
+		return movingFactor, false
+	}
+
+	// There is no step after the last one
+	if matchedStep == ^uint64(0) {
+		return ^uint64(0), true
+	}
+
+	return t.stepStartAt(matchedStep + 1), true
+}
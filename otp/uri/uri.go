@@ -0,0 +1,329 @@
+// Package uri parses and formats otpauth Key URIs.
+//
+// References:
+//   - https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+//   - https://docs.yubico.com/yesdk/users-manual/application-oath/uri-string-format.html
+package uri
+
+import (
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	Scheme = "otpauth"
+
+	TypeHotp = "hotp"
+	TypeTotp = "totp"
+)
+
+var (
+	ErrInvalidScheme    = errors.New("invalid otpauth scheme")
+	ErrInvalidType      = errors.New("invalid otp type")
+	ErrMissingSecret    = errors.New("missing secret")
+	ErrMissingCounter   = errors.New("missing counter")
+	ErrIssuerMismatch   = errors.New("issuer of the label and the parameter differ")
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+// Encoding of the secret. The spec asks to omit the padding.
+var SecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is the content of an otpauth Key URI.
+//
+// Parameters, that are not set in the URI, keep their zero value.
+// It is up to the caller to apply the defaults of the otp type.
+type Key struct {
+	Type      string
+	Issuer    string
+	Account   string
+	Secret    []byte
+	Algorithm string
+	Digits    uint
+	Period    uint
+	Counter   uint64
+
+	// The T0 epoch of RFC 6238 in unix seconds.
+	// It is not part of the spec and written as the t0 parameter.
+	T0 int64
+
+	// A fixed truncation offset instead of the dynamic truncation.
+	// It is not part of the spec and written as the offset parameter.
+	Offset *uint8
+
+	// The characters of the code, if they are not decimal digits.
+	// It is not part of the spec and written as the alphabet parameter.
+	Alphabet string
+
+	// Parameters, that are not part of the spec, like the image, color
+	// or icon parameters of FreeOTP, Aegis and 2FAS. They are kept as
+	// they are, so they can be written back.
+	Extensions url.Values
+}
+
+// The parameters, that are parsed into the fields of Key.
+var knownParameters = map[string]bool{
+	"secret":    true,
+	"issuer":    true,
+	"algorithm": true,
+	"digits":    true,
+	"period":    true,
+	"counter":   true,
+	"t0":        true,
+	"offset":    true,
+	"alphabet":  true,
+}
+
+// Parse a Key URI strictly according to the spec.
+//
+// Example:
+//
+//	key, err := Parse("otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+func Parse(rawUri string) (*Key, error) {
+	return parse(rawUri, false)
+}
+
+// Parse a Key URI and accept deviations from the spec, that are
+// common for legacy URIs:
+//
+//   - labels in the form account:issuer, if the issuer parameter
+//     matches the second part of the label
+//   - issuer prefixes, that differ from the issuer parameter, which
+//     is used instead, as recommended by the spec
+//   - padded secrets and secrets containing spaces
+//   - hotp URIs without a counter, which start at 0
+func ParseLenient(rawUri string) (*Key, error) {
+	return parse(rawUri, true)
+}
+
+func parse(rawUri string, isLenient bool) (*Key, error) {
+	otpUrl, err := url.Parse(rawUri)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(otpUrl.Scheme, Scheme) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidScheme, otpUrl.Scheme)
+	}
+
+	key := &Key{
+		Type: strings.ToLower(otpUrl.Host),
+	}
+
+	if key.Type != TypeHotp && key.Type != TypeTotp {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidType, otpUrl.Host)
+	}
+
+	query := otpUrl.Query()
+
+	err = key.parseLabel(strings.TrimPrefix(otpUrl.Path, "/"), query.Get("issuer"), isLenient)
+	if err != nil {
+		return nil, err
+	}
+
+	err = key.parseSecret(query.Get("secret"), isLenient)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Algorithm = query.Get("algorithm")
+
+	digitsAsString := query.Get("digits")
+	if digitsAsString != "" {
+		digits, err := strconv.ParseUint(digitsAsString, 10, 0)
+		if err != nil || digits == 0 {
+			return nil, fmt.Errorf("%w: digits %q", ErrInvalidParameter, digitsAsString)
+		}
+
+		key.Digits = uint(digits)
+	}
+
+	periodAsString := query.Get("period")
+	if periodAsString != "" {
+		period, err := strconv.ParseUint(periodAsString, 10, 0)
+		if err != nil || period == 0 {
+			return nil, fmt.Errorf("%w: period %q", ErrInvalidParameter, periodAsString)
+		}
+
+		key.Period = uint(period)
+	}
+
+	counterAsString := query.Get("counter")
+	if counterAsString != "" {
+		counter, err := strconv.ParseUint(counterAsString, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: counter: %v", ErrInvalidParameter, err)
+		}
+
+		key.Counter = counter
+	} else if key.Type == TypeHotp && !isLenient {
+		return nil, ErrMissingCounter
+	}
+
+	t0AsString := query.Get("t0")
+	if t0AsString != "" {
+		t0, err := strconv.ParseInt(t0AsString, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: t0: %v", ErrInvalidParameter, err)
+		}
+
+		key.T0 = t0
+	}
+
+	offsetAsString := query.Get("offset")
+	if offsetAsString != "" {
+		offset, err := strconv.ParseUint(offsetAsString, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%w: offset: %v", ErrInvalidParameter, err)
+		}
+
+		truncationOffset := uint8(offset)
+		key.Offset = &truncationOffset
+	}
+
+	key.Alphabet = query.Get("alphabet")
+
+	for name, values := range query {
+		if knownParameters[name] {
+			continue
+		}
+
+		if key.Extensions == nil {
+			key.Extensions = url.Values{}
+		}
+
+		key.Extensions[name] = values
+	}
+
+	return key, nil
+}
+
+// Split the label into issuer and account.
+// The label is already unescaped, so %3A and %20 are handled as well.
+func (k *Key) parseLabel(label string, issuerParameter string, isLenient bool) error {
+	prefix, account, hasPrefix := strings.Cut(label, ":")
+	if !hasPrefix {
+		k.Account = strings.TrimSpace(label)
+		k.Issuer = issuerParameter
+		return nil
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	account = strings.TrimSpace(account)
+
+	if issuerParameter == "" || issuerParameter == prefix {
+		k.Issuer = prefix
+		k.Account = account
+		return nil
+	}
+
+	if !isLenient {
+		return fmt.Errorf("%w: %q and %q", ErrIssuerMismatch, prefix, issuerParameter)
+	}
+
+	// Legacy URIs put the issuer after the account
+	if strings.EqualFold(issuerParameter, account) && !strings.EqualFold(issuerParameter, prefix) {
+		k.Issuer = issuerParameter
+		k.Account = prefix
+		return nil
+	}
+
+	// The spec prefers the issuer parameter, if the prefix differs
+	k.Issuer = issuerParameter
+	k.Account = account
+	return nil
+}
+
+func (k *Key) parseSecret(encodedSecret string, isLenient bool) error {
+	if isLenient {
+		encodedSecret = strings.ReplaceAll(encodedSecret, " ", "")
+		encodedSecret = strings.TrimRight(encodedSecret, "=")
+	}
+
+	if encodedSecret == "" {
+		return ErrMissingSecret
+	}
+
+	secret, err := SecretEncoding.DecodeString(strings.ToUpper(encodedSecret))
+	if err != nil {
+		return fmt.Errorf("%w: secret: %v", ErrInvalidParameter, err)
+	}
+
+	k.Secret = secret
+	return nil
+}
+
+// The label in the form issuer:account, or just the account,
+// if there is no issuer.
+func (k *Key) Label() string {
+	if k.Issuer == "" {
+		return k.Account
+	}
+
+	return k.Issuer + ":" + k.Account
+}
+
+// Format the key as Key URI.
+// The issuer is written to the label and the parameter, as recommended
+// by the spec. Parameters with a zero value are omitted, except for the
+// counter of hotp keys. Extensions never replace the known parameters.
+func (k *Key) String() string {
+	otpUrl := &url.URL{
+		Scheme: Scheme,
+		Host:   k.Type,
+		Path:   "/" + k.Label(),
+	}
+
+	query := url.Values{}
+
+	for name, values := range k.Extensions {
+		if knownParameters[name] {
+			continue
+		}
+
+		query[name] = values
+	}
+
+	query.Set("secret", SecretEncoding.EncodeToString(k.Secret))
+
+	if k.Issuer != "" {
+		query.Set("issuer", k.Issuer)
+	}
+
+	if k.Algorithm != "" {
+		query.Set("algorithm", strings.ToUpper(k.Algorithm))
+	}
+
+	if k.Digits != 0 {
+		query.Set("digits", fmt.Sprint(k.Digits))
+	}
+
+	if k.Type == TypeHotp {
+		query.Set("counter", fmt.Sprint(k.Counter))
+	}
+
+	if k.Period != 0 {
+		query.Set("period", fmt.Sprint(k.Period))
+	}
+
+	if k.T0 != 0 {
+		query.Set("t0", fmt.Sprint(k.T0))
+	}
+
+	if k.Offset != nil {
+		query.Set("offset", fmt.Sprint(*k.Offset))
+	}
+
+	if k.Alphabet != "" {
+		query.Set("alphabet", k.Alphabet)
+	}
+
+	// Authenticator apps expect spaces to be encoded as %20
+	otpUrl.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	return otpUrl.String()
+}
@@ -0,0 +1,194 @@
+package uri_test
+
+import (
+	"errors"
+	"testing"
+
+	"bode.fun/otp/uri"
+	"github.com/matryer/is"
+)
+
+// This test uses the example of the Key URI format
+// https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func Test_Parse(t *testing.T) {
+	is := is.New(t)
+
+	key, err := uri.Parse("otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA1&digits=6&period=30")
+	is.NoErr(err)
+
+	is.Equal(uri.TypeTotp, key.Type)
+	is.Equal("ACME Co", key.Issuer)
+	is.Equal("john.doe@email.com", key.Account)
+	is.Equal("SHA1", key.Algorithm)
+	is.Equal(uint(6), key.Digits)
+	is.Equal(uint(30), key.Period)
+	is.Equal(20, len(key.Secret))
+}
+
+func Test_ParseLabel(t *testing.T) {
+	is := is.New(t)
+
+	{
+		// The separator can be encoded and followed by spaces
+		key, err := uri.Parse("otpauth://totp/Example%3A%20%20alice@google.com?secret=JBSWY3DPEHPK3PXP")
+		is.NoErr(err)
+		is.Equal("Example", key.Issuer)
+		is.Equal("alice@google.com", key.Account)
+	}
+
+	{
+		// The issuer parameter is used, if the label has no prefix
+		key, err := uri.Parse("otpauth://totp/alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+		is.NoErr(err)
+		is.Equal("Example", key.Issuer)
+		is.Equal("alice@google.com", key.Account)
+	}
+
+	{
+		_, err := uri.Parse("otpauth://totp/alice@google.com:Example?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+		is.True(errors.Is(err, uri.ErrIssuerMismatch))
+	}
+
+	{
+		// Legacy URIs put the issuer after the account
+		key, err := uri.ParseLenient("otpauth://totp/alice@google.com:Example?secret=JBSWY3DPEHPK3PXP%3D%3D%3D%3D&issuer=Example")
+		is.NoErr(err)
+		is.Equal("Example", key.Issuer)
+		is.Equal("alice@google.com", key.Account)
+	}
+
+	{
+		// The issuer parameter is used, if the prefix differs
+		key, err := uri.ParseLenient("otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=github")
+		is.NoErr(err)
+		is.Equal("github", key.Issuer)
+		is.Equal("alice", key.Account)
+
+		key, err = uri.ParseLenient("otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example%20Inc")
+		is.NoErr(err)
+		is.Equal("Example Inc", key.Issuer)
+		is.Equal("alice", key.Account)
+
+		_, err = uri.Parse("otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=github")
+		is.True(errors.Is(err, uri.ErrIssuerMismatch))
+	}
+}
+
+func Test_ParseErrors(t *testing.T) {
+	is := is.New(t)
+
+	{
+		_, err := uri.Parse("https://totp/alice?secret=JBSWY3DPEHPK3PXP")
+		is.True(errors.Is(err, uri.ErrInvalidScheme))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://motp/alice?secret=JBSWY3DPEHPK3PXP")
+		is.True(errors.Is(err, uri.ErrInvalidType))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://totp/alice")
+		is.True(errors.Is(err, uri.ErrMissingSecret))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP")
+		is.True(errors.Is(err, uri.ErrMissingCounter))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=0")
+		is.True(errors.Is(err, uri.ErrInvalidParameter))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=0")
+		is.True(errors.Is(err, uri.ErrInvalidParameter))
+	}
+
+	{
+		// The counter is a 64 bit value
+		key, err := uri.Parse("otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=18446744073709551615")
+		is.NoErr(err)
+		is.Equal(^uint64(0), key.Counter)
+	}
+}
+
+func Test_RoundTrip(t *testing.T) {
+	is := is.New(t)
+
+	keys := []*uri.Key{
+		{
+			Type:      uri.TypeTotp,
+			Issuer:    "ACME Co",
+			Account:   "john.doe@email.com",
+			Secret:    []byte("12345678901234567890"),
+			Algorithm: "SHA256",
+			Digits:    8,
+			Period:    60,
+			T0:        1000,
+		},
+		{
+			Type:    uri.TypeHotp,
+			Issuer:  "Example",
+			Account: "alice:with:colons",
+			Secret:  []byte("12345678901234567890"),
+			Counter: 42,
+		},
+		{
+			Type:     uri.TypeHotp,
+			Account:  "alice",
+			Secret:   []byte("12345678901234567890"),
+			Offset:   new(uint8),
+			Alphabet: "0123456789ABCDEF",
+		},
+	}
+
+	for _, key := range keys {
+		parsed, err := uri.Parse(key.String())
+		is.NoErr(err)
+		is.Equal(key, parsed)
+	}
+
+	is.Equal(
+		"otpauth://totp/ACME%20Co:john.doe@email.com?algorithm=SHA256&digits=8&issuer=ACME%20Co&period=60&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&t0=1000",
+		keys[0].String(),
+	)
+}
+
+func Test_Extensions(t *testing.T) {
+	is := is.New(t)
+
+	rawUri := "otpauth://totp/Example:alice@google.com?color=%23ff0000&icon=example&image=https%3A%2F%2Fexample.com%2Flogo.png&issuer=Example&lock=true&secret=JBSWY3DPEHPK3PXP"
+
+	key, err := uri.Parse(rawUri)
+	is.NoErr(err)
+	is.Equal("https://example.com/logo.png", key.Extensions.Get("image"))
+	is.Equal("#ff0000", key.Extensions.Get("color"))
+	is.Equal("true", key.Extensions.Get("lock"))
+	is.Equal("", key.Extensions.Get("secret"))
+
+	is.Equal(rawUri, key.String())
+
+	// Extensions can not replace known parameters
+	key.Extensions.Set("secret", "AAAAAAAA")
+	is.Equal(rawUri, key.String())
+}
+
+func Test_Truncation(t *testing.T) {
+	is := is.New(t)
+
+	{
+		key, err := uri.Parse("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&offset=4&alphabet=0123456789ABCDEF")
+		is.NoErr(err)
+		is.Equal(uint8(4), *key.Offset)
+		is.Equal("0123456789ABCDEF", key.Alphabet)
+		is.Equal(0, len(key.Extensions))
+	}
+
+	{
+		_, err := uri.Parse("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&offset=256")
+		is.True(errors.Is(err, uri.ErrInvalidParameter))
+	}
+}